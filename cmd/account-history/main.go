@@ -1,11 +1,16 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/cristalhq/aconfig"
 	"github.com/cristalhq/aconfig/aconfigyaml"
@@ -13,15 +18,32 @@ import (
 	"github.com/jwiklund/ah/control"
 	"github.com/jwiklund/ah/csv"
 	"github.com/jwiklund/ah/history"
+	"github.com/jwiklund/ah/money"
 	"github.com/jwiklund/ah/view"
+	"golang.org/x/term"
 	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
-	Assets   string `default:"" usage:"Assets directory"`
-	Accounts string `default:"accounts.txt" usage:"Accounts storage file"`
-	Port     int    `default:"8080" usage:"Listen port"`
-	Plugins  string `default:"plugins.yaml" usage:"Plugins yaml"`
+	Assets     string         `default:"" usage:"Assets directory"`
+	Accounts   string         `default:"accounts.txt" usage:"Accounts storage file"`
+	Storage    string         `default:"yaml" usage:"Accounts storage, yaml or sqlite"`
+	Port       int            `default:"8080" usage:"Listen port"`
+	Plugins    string         `default:"plugins.yaml" usage:"Plugins yaml"`
+	Decimals   int            `default:"2" usage:"Number of decimals (minor units) in amounts"`
+	Currency   string         `default:"SEK" usage:"Reporting currency"`
+	Rates      string         `default:"rates.yaml" usage:"Exchange rates yaml or csv"`
+	Autosave   time.Duration  `default:"0" usage:"Save changes once they have been idle this long, 0 disables autosave"`
+	Watch      time.Duration  `default:"2s" usage:"Check the accounts file for changes this often, 0 disables"`
+	Backups    int            `default:"10" usage:"Number of timestamped backups to keep"`
+	Git        bool           `default:"false" usage:"Commit every save to a git repository next to the accounts file instead of keeping backups"`
+	Prices     string         `default:"prices.yaml" usage:"Security prices yaml or csv"`
+	FlowTiming string         `default:"middle" usage:"When changes are assumed to happen within a period for returns, start, middle or end"`
+	CPI        string         `default:"cpi.yaml" usage:"Consumer price index per currency yaml or csv, for real values"`
+	Encrypt    bool           `default:"false" usage:"Encrypt the accounts file, prompting for a passphrase unless one is configured"`
+	KeyFile    string         `default:"" usage:"File containing the encryption passphrase"`
+	Passphrase string         `flag:"-" default:"" usage:"Encryption passphrase, set through APP_PASSPHRASE"`
+	Tags       map[string]any `flag:"-" env:"-" usage:"Tag definitions"`
 }
 
 type PluginConfig struct {
@@ -45,33 +67,326 @@ func main() {
 	flagSet := loader.Flags()
 	initHistory := flagSet.Bool("init-history", false, "Initialize history if it does not exist")
 	addYear := flagSet.String("add-year", "", "Add year")
+	addDate := flagSet.String("add-date", "", "Add date (YYYY, YYYY-Qn or YYYY-MM)")
+	revalue := flagSet.String("revalue", "", "Revalue holdings from prices at date (YYYY, YYYY-Qn or YYYY-MM)")
+	listBackups := flagSet.Bool("list-backups", false, "List backups and exit")
+	restore := flagSet.String("restore", "", "Restore backup and exit")
+	migrateStorage := flagSet.String("migrate-storage", "", "Copy the accounts to another storage and exit, as storage:file (e.g. sqlite:accounts.db)")
+	migrate := flagSet.Bool("migrate", false, "Migrate the accounts file to the newest schema version and exit")
+	dryRun := flagSet.Bool("dry-run", false, "Print what -migrate would change without saving")
+	encrypt := flagSet.Bool("encrypt", false, "Encrypt the accounts file and exit")
+	decrypt := flagSet.Bool("decrypt", false, "Decrypt the accounts file and exit")
+	validate := flagSet.Bool("validate", false, "Validate the accounts file and exit, with status 1 if there are errors")
 	if err := loader.Load(); err != nil {
 		panic(err)
 	}
+	if config.Git {
+		config.Backups = 0
+	}
 	pluginConfig, err := loadPluginConfig(userDir, config.Plugins)
 	if err != nil {
 		log.Fatal(err)
 		return
 	}
-	serve(config, pluginConfig, *initHistory, *addYear)
+	if *listBackups {
+		printBackups(config)
+		return
+	}
+	key, err := encryptionKey(config, config.Encrypt || *encrypt)
+	if err != nil {
+		log.Fatal(err)
+		return
+	}
+	if *restore != "" {
+		restoreBackup(config, key, *restore)
+		return
+	}
+	if *migrateStorage != "" {
+		copyStorage(config, key, *migrateStorage)
+		return
+	}
+	if *migrate {
+		migrateSchema(config, key, *dryRun)
+		return
+	}
+	if *encrypt || *decrypt {
+		convertEncryption(config, key, *encrypt)
+		return
+	}
+	if *addDate == "" {
+		addDate = addYear
+	}
+	var rates *history.Table
+	ratesPath, err := configPath(userDir, config.Rates, "rates.yaml")
+	if err == nil && ratesPath != "" {
+		rates, err = history.LoadTable(ratesPath)
+	}
+	if err != nil {
+		log.Fatal(err)
+		return
+	}
+	var prices *history.Table
+	pricesPath, err := configPath(userDir, config.Prices, "prices.yaml")
+	if err == nil && pricesPath != "" {
+		prices, err = history.LoadTable(pricesPath)
+	}
+	if err != nil {
+		log.Fatal(err)
+		return
+	}
+	var cpi *history.Table
+	cpiPath, err := configPath(userDir, config.CPI, "cpi.yaml")
+	if err == nil && cpiPath != "" {
+		cpi, err = history.LoadTable(cpiPath)
+	}
+	if err != nil {
+		log.Fatal(err)
+		return
+	}
+	tags, err := history.ParseTagDefinitions(config.Tags)
+	if err != nil {
+		log.Fatal(err)
+		return
+	}
+	if *validate {
+		validateAccounts(config, key, rates, tags)
+		return
+	}
+	serve(config, key, pluginConfig, rates, prices, cpi, tags, *initHistory, *addDate, *revalue)
 }
 
-func loadPluginConfig(userDir, path string) (PluginConfig, error) {
-	var config PluginConfig
+func printBackups(config Config) {
+	backups, err := history.Backups(config.Accounts)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, backup := range backups {
+		fmt.Printf("%s\t%s\t%d\n", backup.Name, backup.Time.Format("2006-01-02 15:04:05"), backup.Size)
+	}
+}
+
+func restoreBackup(config Config, key *history.Key, name string) {
+	if err := money.SetMinorUnits(config.Decimals); err != nil {
+		log.Fatal(err)
+	}
+	accounts, err := openAccounts(config, key, true)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := accounts.Restore(config.Accounts, name, config.Backups); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Restored %s\n", name)
+}
+
+func copyStorage(config Config, key *history.Key, target string) {
+	if err := money.SetMinorUnits(config.Decimals); err != nil {
+		log.Fatal(err)
+	}
+	name, filename, ok := strings.Cut(target, ":")
+	if !ok || filename == "" {
+		log.Fatalf("Invalid migration target %s, expected storage:file", target)
+	}
+	storage, err := history.StorageFor(name, key)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if _, err := os.Stat(filename); !os.IsNotExist(err) {
+		log.Fatalf("File already exists: %s", filename)
+	}
+	accounts, err := openAccounts(config, key, false)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := accounts.SaveAs(storage, filename); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Migrated %s to %s %s\n", config.Accounts, name, filename)
+}
+
+func migrateSchema(config Config, key *history.Key, dryRun bool) {
+	if err := money.SetMinorUnits(config.Decimals); err != nil {
+		log.Fatal(err)
+	}
+	storage, err := history.StorageFor(config.Storage, key)
+	if err != nil {
+		log.Fatal(err)
+	}
+	yamlStorage, ok := storage.(history.YAML)
+	if !ok {
+		log.Fatalf("Schema migrations only apply to the yaml storage")
+	}
+	report, err := yamlStorage.Migrations(config.Accounts)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if report.From == report.To {
+		fmt.Printf("%s is at schema version %d\n", config.Accounts, report.To)
+		return
+	}
+	fmt.Printf("%s is at schema version %d, migrating to %d\n", config.Accounts, report.From, report.To)
+	for _, migration := range history.Migrations {
+		if migration.Version <= report.From {
+			continue
+		}
+		fmt.Printf("  %d: %s\n", migration.Version, migration.Description)
+		for _, change := range report.Changes[migration.Version] {
+			fmt.Printf("    %s\n", change)
+		}
+	}
+	if dryRun {
+		return
+	}
+	accounts, err := openAccounts(config, key, false)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := accounts.Save(config.Accounts, config.Backups); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Saved %s\n", config.Accounts)
+}
+
+func convertEncryption(config Config, key *history.Key, encrypt bool) {
+	if key == nil {
+		log.Fatalf("%s is not encrypted", config.Accounts)
+	}
+	if !encrypt {
+		if err := history.DecryptFile(config.Accounts, key); err != nil {
+			log.Fatal(err)
+		}
+		if err := history.ConvertJournal(config.Accounts, key, nil); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Decrypted %s\n", config.Accounts)
+		return
+	}
+	if history.Committed(config.Accounts) {
+		fmt.Fprintf(os.Stderr, "Warning, the git history of %s keeps its unencrypted versions, only new commits are encrypted\n", config.Accounts)
+	}
+	if err := history.EncryptFile(config.Accounts, key); err != nil {
+		log.Fatal(err)
+	}
+	if err := history.ConvertJournal(config.Accounts, nil, key); err != nil {
+		log.Fatal(err)
+	}
+	backups, err := history.EncryptBackups(config.Accounts, key)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Encrypted %s and %d backups\n", config.Accounts, backups)
+}
+
+func encryptionKey(config Config, encrypt bool) (*history.Key, error) {
+	encrypted, err := history.IsEncrypted(config.Accounts)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if !encrypted && !encrypt {
+		return nil, nil
+	}
+	passphrase := config.Passphrase
+	if passphrase == "" && config.KeyFile != "" {
+		content, err := os.ReadFile(config.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		passphrase = strings.TrimSpace(string(content))
+	}
+	if passphrase == "" {
+		if passphrase, err = readPassphrase(!encrypted); err != nil {
+			return nil, err
+		}
+	}
+	return history.NewKey(passphrase)
+}
+
+func readPassphrase(confirm bool) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("No passphrase configured and stdin is not a terminal")
+	}
+	fmt.Fprint(os.Stderr, "Passphrase: ")
+	passphrase, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil || !confirm {
+		return string(passphrase), err
+	}
+	fmt.Fprint(os.Stderr, "Repeat passphrase: ")
+	again, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	if string(again) != string(passphrase) {
+		return "", fmt.Errorf("Passphrases do not match")
+	}
+	return string(passphrase), nil
+}
+
+func openAccounts(config Config, key *history.Key, initHistory bool) (*history.Accounts, error) {
+	storage, err := history.StorageFor(config.Storage, key)
+	if err != nil {
+		return nil, err
+	}
+	accounts, err := history.Open(storage, config.Accounts, initHistory)
+	if err != nil || !config.Git {
+		return accounts, err
+	}
+	repository, err := history.OpenRepository(config.Accounts)
+	if err != nil {
+		return nil, err
+	}
+	accounts.SetRepository(repository)
+	return accounts, nil
+}
+
+func validateAccounts(config Config, key *history.Key, rates *history.Table, tags history.TagDefinitions) {
+	if err := money.SetMinorUnits(config.Decimals); err != nil {
+		log.Fatal(err)
+	}
+	accounts, err := openAccounts(config, key, false)
+	if err != nil {
+		log.Fatal(err)
+	}
+	accounts.SetCurrency(config.Currency, rates)
+	accounts.SetTagDefinitions(tags)
+	findings := accounts.Validate()
+	failed := false
+	for _, finding := range findings {
+		fmt.Println(finding)
+		failed = failed || finding.Severity == history.SeverityError
+	}
+	if failed {
+		os.Exit(1)
+	}
+	if len(findings) == 0 {
+		fmt.Printf("%s is valid\n", config.Accounts)
+	}
+}
+
+func configPath(userDir, path, defaultPath string) (string, error) {
 	if path == "" {
-		return config, nil
-	}
-	var pluginPath string
-	if _, err := os.Stat(path); os.IsNotExist(err) {
-		pluginPath = strings.Join([]string{userDir, "account-history", path}, string(os.PathSeparator))
-		if _, err := os.Stat(pluginPath); os.IsNotExist(err) {
-			if path == "plugins.yaml" {
-				return config, nil
-			}
-			return config, fmt.Errorf("Plugin file does not exist: %w", err)
+		return "", nil
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		return path, nil
+	}
+	userPath := strings.Join([]string{userDir, "account-history", path}, string(os.PathSeparator))
+	if _, err := os.Stat(userPath); os.IsNotExist(err) {
+		if path == defaultPath {
+			return "", nil
 		}
-	} else {
-		pluginPath = path
+		return "", fmt.Errorf("File does not exist: %w", err)
+	}
+	return userPath, nil
+}
+
+func loadPluginConfig(userDir, path string) (PluginConfig, error) {
+	var config PluginConfig
+	pluginPath, err := configPath(userDir, path, "plugins.yaml")
+	if err != nil || pluginPath == "" {
+		return config, err
 	}
 	pluginFile, err := os.Open(pluginPath)
 	if err != nil {
@@ -84,16 +399,38 @@ func loadPluginConfig(userDir, path string) (PluginConfig, error) {
 	return config, err
 }
 
-func serve(config Config, plugins PluginConfig, initHistory bool, addYear string) {
-	accounts, err := history.Load(config.Accounts, initHistory)
+func serve(config Config, key *history.Key, plugins PluginConfig, rates, prices, cpi *history.Table, tags history.TagDefinitions, initHistory bool, addDate, revalue string) {
+	if err := money.SetMinorUnits(config.Decimals); err != nil {
+		fmt.Printf("invalid decimals: %v\n", err)
+		return
+	}
+	accounts, err := openAccounts(config, key, initHistory)
 	if err != nil {
 		fmt.Printf("could not load history %v\n", err)
 		return
 	}
-	if addYear != "" {
-		err = accounts.AddYear(addYear)
+	if err := accounts.SetCurrency(config.Currency, rates); err != nil {
+		fmt.Printf("could not convert to %s: %v\n", config.Currency, err)
+		return
+	}
+	accounts.SetTagDefinitions(tags)
+	accounts.SetCPI(cpi)
+	if err := accounts.SetFlowTiming(history.FlowTiming(config.FlowTiming)); err != nil {
+		fmt.Printf("Warning, %v\n", err)
+	}
+	for _, finding := range accounts.Validate() {
+		fmt.Println(finding)
+	}
+	if addDate != "" {
+		err = accounts.AddDate(addDate)
 		if err != nil {
-			fmt.Printf("could not add year %s: %v\n", addYear, err)
+			fmt.Printf("could not add date %s: %v\n", addDate, err)
+		}
+	}
+	if revalue != "" {
+		err = accounts.Revalue(revalue, prices)
+		if err != nil {
+			fmt.Printf("could not revalue %s: %v\n", revalue, err)
 		}
 	}
 	renderer, err := view.New(config.Assets)
@@ -110,6 +447,9 @@ func serve(config Config, plugins PluginConfig, initHistory bool, addYear string
 		Accounts:      accounts,
 		Renderer:      renderer,
 		ImportPlugins: importPlugins,
+		Prices:        prices,
+		CPI:           cpi,
+		KeepBackups:   config.Backups,
 	}
 	router := httprouter.New()
 	router.GET("/favicon.ico", controller.Resource("favicon.ico"))
@@ -120,11 +460,41 @@ func serve(config Config, plugins PluginConfig, initHistory bool, addYear string
 	router.POST("/edit/add", controller.EditAdd)
 	router.POST("/edit/amount", controller.EditAmount)
 	router.POST("/edit/change", controller.EditChange)
+	router.POST("/edit/delete/:accountId", controller.EditDelete)
+
+	router.GET("/edit/account/:accountId", controller.EditAccount)
+	router.POST("/edit/account/:accountId/add", controller.EditAccountAdd)
+	router.POST("/edit/account/:accountId/rename", controller.EditAccountRename)
+	router.POST("/edit/account/:accountId/close", controller.EditAccountClose)
+	router.POST("/edit/account/:accountId/reopen", controller.EditAccountReopen)
+	router.POST("/edit/account/:accountId/kind", controller.EditAccountKind)
+	router.POST("/edit/account/:accountId/currency", controller.EditAccountCurrency)
+	router.POST("/edit/account/:accountId/holding", controller.EditAccountHolding)
+	router.POST("/edit/account/:accountId/revalue", controller.EditAccountRevalue)
+	router.POST("/edit/account/:accountId/transfer", controller.EditAccountTransfer)
+	router.POST("/edit/account/:accountId/transfer/delete/:transferId", controller.EditAccountDeleteTransfer)
+	router.POST("/edit/account/:accountId/amount/:year", controller.EditAccountAmount)
+	router.POST("/edit/account/:accountId/change/:year", controller.EditAccountChange)
+	router.POST("/edit/account/:accountId/withdrawal/:year", controller.EditAccountWithdrawal)
+	router.POST("/edit/account/:accountId/income/:year", controller.EditAccountIncome)
+	router.POST("/edit/account/:accountId/fees/:year", controller.EditAccountFees)
+	router.POST("/edit/account/:accountId/delete/:year", controller.EditAccountDelete)
+
+	router.GET("/tags", controller.Tags)
+	router.POST("/tags/add", controller.TagsAdd)
+	router.POST("/tags/remove", controller.TagsRemove)
+	router.POST("/tags/rename", controller.TagsRename)
+
+	router.GET("/backups", controller.Backups)
+	router.POST("/backups/restore/:name", controller.BackupsRestore)
 
-	router.GET("/edit/account/:accountSlug", controller.EditAccount)
-	router.POST("/edit/account/:accountSlug/add", controller.EditAccountAdd)
-	router.POST("/edit/account/:accountSlug/amount/:year", controller.EditAccountAmount)
-	router.POST("/edit/account/:accountSlug/change/:year", controller.EditAccountChange)
+	router.GET("/revisions", controller.Revisions)
+	router.GET("/revisions/:revision", controller.Revision)
+
+	router.GET("/journal", controller.Journal)
+	router.GET("/projection", controller.Projection)
+	router.POST("/undo", controller.Undo)
+	router.POST("/redo", controller.Redo)
 
 	router.GET("/import", controller.Import)
 	router.POST("/import/prepare", controller.PrepareImport)
@@ -133,6 +503,39 @@ func serve(config Config, plugins PluginConfig, initHistory bool, addYear string
 	router.POST("/import/column/:columnId", controller.PrepareImportColumn)
 	router.POST("/import", controller.ImportData)
 
-	fmt.Printf("Listening on http://localhost:%d", config.Port)
-	log.Fatal(http.ListenAndServe(fmt.Sprintf(":%d", config.Port), router))
+	router.GET("/status", controller.Status)
+	router.GET("/conflict", controller.Conflict)
+	router.POST("/conflict/resolve", controller.ConflictResolve)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if config.Watch > 0 {
+		go controller.Watch(ctx, config.Watch)
+	}
+	if config.Autosave > 0 {
+		go controller.Autosave(ctx, config.Autosave)
+	}
+	server := &http.Server{Addr: fmt.Sprintf(":%d", config.Port), Handler: router}
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdown)
+	}()
+	fmt.Printf("Listening on http://localhost:%d\n", config.Port)
+	if err := server.ListenAndServe(); err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
+	if accounts.Dirty() {
+		err := accounts.Save(config.Accounts, config.Backups)
+		if errors.Is(err, history.ErrConflict) {
+			conflict := config.Accounts + ".conflict"
+			err = accounts.SaveCopy(conflict)
+			fmt.Printf("%s was changed by someone else, unsaved changes written to %s\n", config.Accounts, conflict)
+		}
+		if err != nil {
+			log.Fatalf("could not save %s: %v", config.Accounts, err)
+		}
+		fmt.Printf("Saved %s\n", config.Accounts)
+	}
 }
@@ -0,0 +1,46 @@
+package control
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/jwiklund/ah/history"
+)
+
+type Backups struct {
+	Backups []history.Backup
+	Keep    int
+	Message string
+	Error   error
+}
+
+func (c *Control) RenderBackups(w http.ResponseWriter, r *http.Request, message string, err error) {
+	backups, listErr := history.Backups(c.AccountsPath)
+	if err == nil {
+		err = listErr
+	}
+	data := Backups{
+		Backups: backups,
+		Keep:    c.KeepBackups,
+		Message: message,
+		Error:   err,
+	}
+	if err := c.Renderer.Render(templateName("backups", r), w, data); err != nil {
+		fmt.Fprintf(w, "Could not render backups: %v", err)
+	}
+}
+
+func (c *Control) Backups(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	c.RenderBackups(w, r, "", nil)
+}
+
+func (c *Control) BackupsRestore(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	name := p.ByName("name")
+	err := c.Accounts.Restore(c.AccountsPath, name, c.KeepBackups)
+	message := ""
+	if err == nil {
+		message = fmt.Sprintf("Restored %s", name)
+	}
+	c.RenderBackups(w, r, message, err)
+}
@@ -0,0 +1,55 @@
+package control
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/jwiklund/ah/history"
+)
+
+type Conflict struct {
+	Accounts []history.AccountConflict
+	Message  string
+	Error    error
+}
+
+func (c *Control) RenderConflict(w http.ResponseWriter, r *http.Request, message string, err error) {
+	conflicts, conflictErr := c.Accounts.Conflicts(c.AccountsPath)
+	if err == nil {
+		err = conflictErr
+	}
+	data := Conflict{
+		Accounts: conflicts,
+		Message:  message,
+		Error:    err,
+	}
+	if err := c.Renderer.Render(templateName("conflict", r), w, data); err != nil {
+		fmt.Fprintf(w, "Could not render conflict: %v", err)
+	}
+}
+
+func (c *Control) Conflict(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	c.RenderConflict(w, r, "", nil)
+}
+
+func (c *Control) ConflictResolve(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	err := r.ParseForm()
+	if err != nil {
+		c.RenderConflict(w, r, "", err)
+		return
+	}
+	var useDisk []string
+	for key := range r.Form {
+		if id, ok := strings.CutPrefix(key, "side-"); ok && formInput(r, key) == "disk" {
+			useDisk = append(useDisk, id)
+		}
+	}
+	err = c.Accounts.Resolve(c.AccountsPath, useDisk, c.KeepBackups)
+	message := ""
+	if err == nil {
+		message = fmt.Sprintf("Saved %s", c.AccountsPath)
+	}
+	c.RenderConflict(w, r, message, err)
+}
@@ -4,13 +4,16 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/jwiklund/ah/csv"
 	"github.com/jwiklund/ah/history"
+	"github.com/jwiklund/ah/money"
 	"github.com/jwiklund/ah/view"
+	"golang.org/x/exp/slices"
 )
 
 type Control struct {
@@ -18,6 +21,9 @@ type Control struct {
 	Accounts      *history.Accounts
 	Renderer      view.Renderer
 	ImportPlugins map[string]csv.ImportPlugin
+	Prices        *history.Table
+	CPI           *history.Table
+	KeepBackups   int
 }
 
 func (c *Control) Resource(name string) func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
@@ -40,6 +46,47 @@ func templateNameWithPart(base string, r *http.Request, part string) string {
 	return strings.Join(parts, ".")
 }
 
+func viewOptions(r *http.Request, defaultGranularity history.Granularity) history.ViewOptions {
+	query := r.URL.Query()
+	opts := history.ViewOptions{
+		Tag:         query.Get("tag"),
+		Granularity: defaultGranularity,
+	}
+	if query.Has("granularity") {
+		granularity := history.Granularity(query.Get("granularity"))
+		if granularity == history.AsRecorded || slices.Contains(history.Granularities, granularity) {
+			opts.Granularity = granularity
+		}
+	}
+	if real := query.Get("real"); history.ValidDate(real) {
+		opts.Real = history.RollUp(real, history.Yearly)
+	}
+	return opts
+}
+
+func (c *Control) realBases(currency string) []string {
+	var years []string
+	for _, date := range c.CPI.Dates(currency) {
+		year := history.RollUp(date, history.Yearly)
+		if !slices.Contains(years, year) {
+			years = append(years, year)
+		}
+	}
+	return years
+}
+
+// queryParam falls back to the page that issued an htmx request.
+func queryParam(r *http.Request, key string) string {
+	if r.URL.Query().Has(key) {
+		return r.URL.Query().Get(key)
+	}
+	current, err := url.Parse(r.Header.Get("Hx-Current-Url"))
+	if err != nil {
+		return ""
+	}
+	return current.Query().Get(key)
+}
+
 func isHx(r *http.Request) bool {
 	hx, _ := r.Header["Hx-Request"]
 	return len(hx) == 1 && hx[0] == "true"
@@ -49,14 +96,24 @@ func formInput(r *http.Request, key string) string {
 	return strings.TrimSpace(rawFormInput(r, key))
 }
 
-func formIntInput(r *http.Request, key string) (int, error) {
+func formAmountInput(r *http.Request, key string) (money.Amount, error) {
 	input := formInput(r, key)
 	if input == "" {
 		return 0, fmt.Errorf("no value for %s", key)
 	}
-	input = strings.ReplaceAll(input, ",", "")
-	input = strings.ReplaceAll(input, " ", "")
-	value, err := strconv.Atoi(input)
+	value, err := money.Parse(input)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value for %s: %v", key, err)
+	}
+	return value, nil
+}
+
+func formFloatInput(r *http.Request, key string) (float64, error) {
+	input := strings.ReplaceAll(formInput(r, key), ",", ".")
+	if input == "" {
+		return 0, fmt.Errorf("no value for %s", key)
+	}
+	value, err := strconv.ParseFloat(input, 64)
 	if err != nil {
 		return 0, fmt.Errorf("invalid value for %s: %v", key, err)
 	}
@@ -70,19 +127,16 @@ func rawFormInput(r *http.Request, key string) string {
 	return ""
 }
 
-func slugAndIntValue(r *http.Request) (string, int, error) {
+func idAndAmountValue(r *http.Request) (string, money.Amount, error) {
 	for key, value := range r.Form {
 		if len(value) != 1 {
 			continue
 		}
-		input := value[0]
-		input = strings.ReplaceAll(input, ",", "")
-		input = strings.ReplaceAll(input, " ", "")
-		intValue, err := strconv.Atoi(input)
+		amount, err := money.Parse(value[0])
 		if err != nil {
 			return "", 0, err
 		}
-		return key, intValue, nil
+		return key, amount, nil
 	}
 	return "", 0, errors.New("no form values")
 }
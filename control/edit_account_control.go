@@ -7,86 +7,297 @@ import (
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/jwiklund/ah/history"
+	"github.com/jwiklund/ah/money"
 )
 
 type EditAccount struct {
-	Name    string
-	Slug    string
-	History []history.SummaryEntry
-	Total   Total
-	Message string
-	Error   error
+	ID            string
+	Name          string
+	Kind          history.Kind
+	Kinds         []history.Kind
+	Closed        string
+	CurrentDate   string
+	Currency      string
+	Reporting     string
+	Native        bool
+	History       []history.SummaryEntry
+	Returns       []history.Return
+	Holdings      []history.HoldingEntry
+	Valued        map[string]bool
+	Transfers     []history.TransferLeg
+	Accounts      []history.CurrentEntry
+	HasPrices     bool
+	Total         Total
+	Granularity   history.Granularity
+	Granularities []history.Granularity
+	Real          string
+	RealBases     []string
+	Message       string
+	Error         error
 }
 
-func (c *Control) RenderEditAccount(w http.ResponseWriter, r *http.Request, slug, message string, err error) {
+func (c *Control) RenderEditAccount(w http.ResponseWriter, r *http.Request, id, message string, err error) {
+	opts := viewOptions(r, history.AsRecorded)
+	opts.Native = r.URL.Query().Get("currency") != "reporting"
 	edit := EditAccount{
-		Slug:    slug,
-		Message: message,
-		Error:   err,
+		ID:            id,
+		Reporting:     c.Accounts.Currency(),
+		Native:        opts.Native,
+		Granularity:   opts.Granularity,
+		Granularities: history.Granularities,
+		Real:          opts.Real,
+		HasPrices:     len(c.Prices.Keys()) > 0,
+		Message:       message,
+		Error:         err,
 	}
-	edit.Name, edit.History, err = c.Accounts.AccountHistory(slug)
+	account, err := c.Accounts.Account(id)
 	if edit.Error == nil {
 		edit.Error = err
 	}
+	edit.Currency = account.Currency
+	if edit.Native {
+		edit.RealBases = c.realBases(account.Currency)
+	} else {
+		edit.RealBases = c.realBases(edit.Reporting)
+	}
+	edit.Closed = account.Closed
+	edit.Kind = account.Kind
+	if edit.Kind == "" {
+		edit.Kind = history.Asset
+	}
+	edit.Kinds = history.Kinds
+	edit.CurrentDate = c.Accounts.CurrentDate()
+	edit.Name, edit.History, err = c.Accounts.AccountHistory(id, opts)
+	if edit.Error == nil {
+		edit.Error = err
+	}
+	edit.Returns, err = c.Accounts.AccountReturns(id, opts)
+	if edit.Error == nil {
+		edit.Error = err
+	}
+	edit.Holdings, err = c.Accounts.Holdings(id, opts)
+	if edit.Error == nil {
+		edit.Error = err
+	}
+	edit.Valued = make(map[string]bool)
+	for _, holding := range edit.Holdings {
+		edit.Valued[holding.Year] = true
+	}
+	edit.Transfers, err = c.Accounts.Transfers(id)
+	if edit.Error == nil {
+		edit.Error = err
+	}
+	edit.Accounts = c.Accounts.Current(history.ViewOptions{})
 	for _, h := range edit.History {
 		edit.Total.Assets = h.End
 		edit.Total.Change = edit.Total.Change + h.Change
+		edit.Total.Income = edit.Total.Income + h.Income
+		edit.Total.Fees = edit.Total.Fees + h.Fees
+		edit.Total.Market = edit.Total.Market + h.Market
 		edit.Total.Increase = edit.Total.Increase + h.Increase
+		edit.Total.FX = edit.Total.FX + h.FX
 	}
+	totalReturns(&edit.Total, edit.Returns)
 	if err := c.Renderer.Render(templateName("edit.account", r), w, edit); err != nil {
 		fmt.Fprintf(w, "Could not render edit account: %v", err)
 	}
 }
 
 func (c *Control) EditAccount(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
-	slug := p.ByName("accountSlug")
-	c.RenderEditAccount(w, r, slug, "", nil)
+	id := p.ByName("accountId")
+	c.RenderEditAccount(w, r, id, "", nil)
 }
 
 func (c *Control) EditAccountAdd(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
-	slug := p.ByName("accountSlug")
+	id := p.ByName("accountId")
 	err := r.ParseForm()
 	if err != nil {
-		c.RenderEditAccount(w, r, slug, "", err)
+		c.RenderEditAccount(w, r, id, "", err)
 		return
 	}
 	year := formInput(r, "year")
-	err = c.Accounts.UpdateHistoryBySlugDate(slug, year, func(h history.History) history.History {
+	if err := history.ValidateDate(year); err != nil {
+		c.RenderEditAccount(w, r, id, "", err)
+		return
+	}
+	err = c.Accounts.UpdateHistoryByDate(id, year, func(h history.History) history.History {
 		return h
 	})
-	c.RenderEditAccount(w, r, slug, "", err)
+	c.RenderEditAccount(w, r, id, "", err)
 }
 
-func (c *Control) EditAccountAmount(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
-	slug := p.ByName("accountSlug")
+func (c *Control) EditAccountDelete(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	id := p.ByName("accountId")
 	year := p.ByName("year")
+	err := c.Accounts.DeleteHistory(id, year)
+	message := ""
+	if err == nil {
+		message = fmt.Sprintf("Deleted %s", year)
+	}
+	c.RenderEditAccount(w, r, id, message, err)
+}
+
+func (c *Control) EditAccountClose(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	id := p.ByName("accountId")
 	err := r.ParseForm()
 	if err != nil {
-		c.RenderEditAccount(w, r, slug, "", err)
+		c.RenderEditAccount(w, r, id, "", err)
 		return
 	}
-	amount, err := formIntInput(r, strings.Join([]string{year, "amount"}, "-"))
+	err = c.Accounts.Close(id, formInput(r, "date"))
+	c.RenderEditAccount(w, r, id, "", err)
+}
+
+func (c *Control) EditAccountReopen(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	id := p.ByName("accountId")
+	err := c.Accounts.Reopen(id)
+	c.RenderEditAccount(w, r, id, "", err)
+}
+
+func (c *Control) EditAccountRename(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	id := p.ByName("accountId")
+	err := r.ParseForm()
+	if err != nil {
+		c.RenderEditAccount(w, r, id, "", err)
+		return
+	}
+	err = c.Accounts.Rename(id, formInput(r, "name"))
+	c.RenderEditAccount(w, r, id, "", err)
+}
+
+func (c *Control) EditAccountKind(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	id := p.ByName("accountId")
+	err := r.ParseForm()
 	if err != nil {
-		c.RenderEditAccount(w, r, slug, "", err)
+		c.RenderEditAccount(w, r, id, "", err)
 		return
 	}
-	err = c.Accounts.UpdateAmountBySlug(slug, year, amount)
-	c.RenderEditAccount(w, r, slug, "", err)
+	err = c.Accounts.UpdateKind(id, history.Kind(formInput(r, "kind")))
+	c.RenderEditAccount(w, r, id, "", err)
+}
+
+func (c *Control) EditAccountCurrency(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	id := p.ByName("accountId")
+	err := r.ParseForm()
+	if err != nil {
+		c.RenderEditAccount(w, r, id, "", err)
+		return
+	}
+	err = c.Accounts.UpdateCurrency(id, formInput(r, "currency"))
+	c.RenderEditAccount(w, r, id, "", err)
+}
+
+func (c *Control) EditAccountAmount(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	c.editAccountValue(w, r, p, "amount", c.Accounts.UpdateAmount)
 }
 
 func (c *Control) EditAccountChange(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
-	slug := p.ByName("accountSlug")
+	c.editAccountValue(w, r, p, "change", c.Accounts.UpdateChange)
+}
+
+func (c *Control) EditAccountWithdrawal(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	c.editAccountValue(w, r, p, "withdrawal", c.Accounts.UpdateWithdrawal)
+}
+
+func (c *Control) EditAccountIncome(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	c.editAccountValue(w, r, p, "income", c.Accounts.UpdateIncome)
+}
+
+func (c *Control) EditAccountFees(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	c.editAccountValue(w, r, p, "fees", c.Accounts.UpdateFees)
+}
+
+func (c *Control) editAccountValue(w http.ResponseWriter, r *http.Request, p httprouter.Params, field string, update func(string, string, money.Amount) error) {
+	id := p.ByName("accountId")
 	year := p.ByName("year")
 	err := r.ParseForm()
 	if err != nil {
-		c.RenderEditAccount(w, r, slug, "", err)
+		c.RenderEditAccount(w, r, id, "", err)
+		return
+	}
+	value, err := formAmountInput(r, strings.Join([]string{year, field}, "-"))
+	if err != nil {
+		c.RenderEditAccount(w, r, id, "", err)
+		return
+	}
+	err = update(id, year, value)
+	c.RenderEditAccount(w, r, id, "", err)
+}
+
+func (c *Control) EditAccountHolding(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	id := p.ByName("accountId")
+	err := r.ParseForm()
+	if err != nil {
+		c.RenderEditAccount(w, r, id, "", err)
+		return
+	}
+	date := formInput(r, "date")
+	if err := history.ValidateDate(date); err != nil {
+		c.RenderEditAccount(w, r, id, "", err)
+		return
+	}
+	units, err := formFloatInput(r, "units")
+	if err != nil {
+		c.RenderEditAccount(w, r, id, "", err)
+		return
+	}
+	price, err := formFloatInput(r, "price")
+	if err != nil {
+		c.RenderEditAccount(w, r, id, "", err)
+		return
+	}
+	err = c.Accounts.UpdateHolding(id, date, history.Holding{
+		Security: formInput(r, "security"),
+		Units:    units,
+		Price:    price,
+	})
+	c.RenderEditAccount(w, r, id, "", err)
+}
+
+func (c *Control) EditAccountRevalue(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	id := p.ByName("accountId")
+	err := r.ParseForm()
+	if err != nil {
+		c.RenderEditAccount(w, r, id, "", err)
 		return
 	}
-	change, err := formIntInput(r, strings.Join([]string{year, "change"}, "-"))
+	date := formInput(r, "date")
+	err = c.Accounts.RevalueAccount(id, date, c.Prices)
+	message := ""
+	if err == nil {
+		message = fmt.Sprintf("Revalued holdings for %s", date)
+	}
+	c.RenderEditAccount(w, r, id, message, err)
+}
+
+func (c *Control) EditAccountTransfer(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	id := p.ByName("accountId")
+	err := r.ParseForm()
+	if err != nil {
+		c.RenderEditAccount(w, r, id, "", err)
+		return
+	}
+	amount, err := formAmountInput(r, "amount")
 	if err != nil {
-		c.RenderEditAccount(w, r, slug, "", err)
+		c.RenderEditAccount(w, r, id, "", err)
 		return
 	}
-	err = c.Accounts.UpdateChangeBySlug(slug, year, change)
-	c.RenderEditAccount(w, r, slug, "", err)
+	to := formInput(r, "to")
+	transfer, err := c.Accounts.Transfer(id, to, formInput(r, "date"), amount)
+	message := ""
+	if err == nil {
+		message = fmt.Sprintf("Transferred %s to %s (%s)", amount.Human(), to, transfer)
+	}
+	c.RenderEditAccount(w, r, id, message, err)
+}
+
+func (c *Control) EditAccountDeleteTransfer(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	id := p.ByName("accountId")
+	transfer := p.ByName("transferId")
+	err := c.Accounts.DeleteTransfer(transfer)
+	message := ""
+	if err == nil {
+		message = fmt.Sprintf("Deleted transfer %s", transfer)
+	}
+	c.RenderEditAccount(w, r, id, message, err)
 }
@@ -9,16 +9,21 @@ import (
 )
 
 type Edit struct {
-	Current []history.CurrentEntry
-	Message string
-	Error   error
+	Current    []history.CurrentEntry
+	Archived   bool
+	Unbalanced []history.TransferLeg
+	Message    string
+	Error      error
 }
 
 func (c *Control) RenderEdit(w http.ResponseWriter, r *http.Request, message string, err error) {
+	archived := queryParam(r, "archived") == "true"
 	edit := Edit{
-		Current: c.Accounts.Current(),
-		Message: message,
-		Error:   err,
+		Current:    c.Accounts.Current(history.ViewOptions{Native: true, Archived: archived}),
+		Archived:   archived,
+		Unbalanced: c.Accounts.UnbalancedTransfers(),
+		Message:    message,
+		Error:      err,
 	}
 	if err := c.Renderer.Render(templateName("edit", r), w, edit); err != nil {
 		fmt.Fprintf(w, "Couild not render edit: %v", err)
@@ -43,6 +48,19 @@ func (c *Control) EditAdd(w http.ResponseWriter, r *http.Request, _ httprouter.P
 	c.RenderEdit(w, r, "", err)
 }
 
+func (c *Control) EditDelete(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	id := p.ByName("accountId")
+	name, _, err := c.Accounts.AccountHistory(id, history.ViewOptions{})
+	if err == nil {
+		err = c.Accounts.DeleteAccount(id)
+	}
+	message := ""
+	if err == nil {
+		message = fmt.Sprintf("Deleted %s", name)
+	}
+	c.RenderEdit(w, r, message, err)
+}
+
 func (c *Control) EditAmount(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 	err := r.ParseForm()
 	if err != nil {
@@ -51,9 +69,9 @@ func (c *Control) EditAmount(w http.ResponseWriter, r *http.Request, _ httproute
 	}
 
 	date := c.Accounts.CurrentDate()
-	key, value, err := slugAndIntValue(r)
+	key, value, err := idAndAmountValue(r)
 	if err == nil {
-		err = c.Accounts.UpdateAmountBySlug(key, date, value)
+		err = c.Accounts.UpdateAmount(key, date, value)
 	}
 	c.RenderEdit(w, r, "", err)
 }
@@ -66,9 +84,9 @@ func (c *Control) EditChange(w http.ResponseWriter, r *http.Request, _ httproute
 	}
 
 	date := c.Accounts.CurrentDate()
-	key, value, err := slugAndIntValue(r)
+	key, value, err := idAndAmountValue(r)
 	if err == nil {
-		err = c.Accounts.UpdateChangeBySlug(key, date, value)
+		err = c.Accounts.UpdateChange(key, date, value)
 	}
 	c.RenderEdit(w, r, "", err)
 }
@@ -1,61 +1,132 @@
 package control
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/jwiklund/ah/history"
+	"github.com/jwiklund/ah/money"
 )
 
 func (c *Control) Index(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	tag := r.URL.Query().Get("tag")
-	if err := c.Renderer.Render(templateName("index", r), w, summarize(*c.Accounts, tag)); err != nil {
+	opts := viewOptions(r, history.Yearly)
+	data := summarize(*c.Accounts, opts)
+	data.RealBases = c.realBases(data.Currency)
+	if err := c.Renderer.Render(templateName("index", r), w, data); err != nil {
 		fmt.Fprintf(w, "Could not render index: %v", err)
 	}
 }
 
 func (c *Control) Save(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	c.Accounts.Save(c.AccountsPath)
-	tag := r.URL.Query().Get("tag")
-	if err := c.Renderer.Render(templateName("index", r), w, summarize(*c.Accounts, tag)); err != nil {
+	err := c.Accounts.Save(c.AccountsPath, c.KeepBackups)
+	if errors.Is(err, history.ErrConflict) {
+		c.RenderConflict(w, r, "", err)
+		return
+	}
+	opts := viewOptions(r, history.Yearly)
+	data := summarize(*c.Accounts, opts)
+	data.RealBases = c.realBases(data.Currency)
+	data.Error = err
+	if err := c.Renderer.Render(templateName("index", r), w, data); err != nil {
 		fmt.Fprintf(w, "Could not render index: %v", err)
 	}
 }
 
 type IndexData struct {
-	Years []history.SummaryEntry
-	Total Total
-	Tag   string
-	Tags  []string
+	Currency      string
+	Years         []history.SummaryEntry
+	Returns       []history.Return
+	Projection    []history.ProjectionEntry
+	Total         Total
+	Tag           string
+	Tags          []string
+	Granularity   history.Granularity
+	Granularities []history.Granularity
+	Real          string
+	RealBases     []string
+	Findings      []history.Finding
+	Error         error
 }
 
 type Total struct {
-	Assets   int
-	Increase int
-	Change   int
+	Assets      money.Amount
+	Gross       money.Amount
+	Liabilities money.Amount
+	Liquid      money.Amount
+	Illiquid    money.Amount
+	Increase    money.Amount
+	Change      money.Amount
+	Income      money.Amount
+	Fees        money.Amount
+	Market      money.Amount
+	FX          money.Amount
+	TWR         float64
+	XIRR        *float64
 }
 
-func summarize(a history.Accounts, tag string) IndexData {
+func totalReturns(total *Total, returns []history.Return) {
+	if len(returns) > 0 {
+		total.TWR = returns[len(returns)-1].TWR
+		total.XIRR = returns[len(returns)-1].XIRR
+	}
+}
+
+func summarize(a history.Accounts, opts history.ViewOptions) IndexData {
 	data := IndexData{
-		Tag: tag,
+		Currency:      a.Currency(),
+		Tag:           opts.Tag,
+		Granularity:   opts.Granularity,
+		Granularities: history.Granularities,
+		Real:          opts.Real,
 	}
-	data.Years, data.Tags = a.Summary(data.Tag)
+	data.Years, data.Tags = a.Summary(opts)
+	data.Returns = a.SummaryReturns(opts)
+	data.Findings = a.Validate()
 
-	var totalSum int
-	var totalIncrease int
-	var totalChange int
+	var totalSum money.Amount
+	var totalGross money.Amount
+	var totalLiabilities money.Amount
+	var totalIlliquid money.Amount
+	var totalIncrease money.Amount
+	var totalChange money.Amount
+	var totalIncome money.Amount
+	var totalFees money.Amount
+	var totalMarket money.Amount
+	var totalFX money.Amount
 
 	for _, y := range data.Years {
 		totalSum = y.End
+		totalGross = y.Assets
+		totalLiabilities = y.Liabilities
+		totalIlliquid = y.Illiquid
 		totalIncrease = totalIncrease + y.Increase
 		totalChange = totalChange + y.Change
+		totalIncome = totalIncome + y.Income
+		totalFees = totalFees + y.Fees
+		totalMarket = totalMarket + y.Market
+		totalFX = totalFX + y.FX
 	}
 
 	data.Total = Total{
-		Assets:   totalSum,
-		Increase: totalIncrease,
-		Change:   totalChange,
+		Assets:      totalSum,
+		Gross:       totalGross,
+		Liabilities: totalLiabilities,
+		Liquid:      totalSum - totalIlliquid,
+		Illiquid:    totalIlliquid,
+		Increase:    totalIncrease,
+		Change:      totalChange,
+		Income:      totalIncome,
+		Fees:        totalFees,
+		Market:      totalMarket,
+		FX:          totalFX,
+	}
+	totalReturns(&data.Total, data.Returns)
+	if len(data.Years) > 0 {
+		yearly := opts
+		yearly.Granularity = history.Yearly
+		data.Projection, _ = newProjection(&a, yearly).project(data.Years[len(data.Years)-1])
 	}
 	return data
 }
@@ -0,0 +1,61 @@
+package control
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/jwiklund/ah/history"
+	"golang.org/x/exp/slices"
+)
+
+type Journal struct {
+	Events   []history.Event
+	Filter   history.JournalFilter
+	Accounts []JournalAccount
+	Fields   []string
+}
+
+type JournalAccount struct {
+	ID   string
+	Name string
+}
+
+func (c *Control) Journal(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	query := r.URL.Query()
+	data := Journal{
+		Filter: history.JournalFilter{
+			Account: query.Get("account"),
+			Date:    query.Get("date"),
+			Field:   query.Get("field"),
+		},
+	}
+	for _, event := range c.Accounts.Journal(history.JournalFilter{}) {
+		if !slices.ContainsFunc(data.Accounts, func(a JournalAccount) bool { return a.ID == event.Account }) {
+			data.Accounts = append(data.Accounts, JournalAccount{ID: event.Account, Name: event.Name})
+		}
+		if !slices.Contains(data.Fields, event.Field) {
+			data.Fields = append(data.Fields, event.Field)
+		}
+	}
+	slices.Sort(data.Fields)
+	data.Events = c.Accounts.Journal(data.Filter)
+	if err := c.Renderer.Render(templateName("journal", r), w, data); err != nil {
+		fmt.Fprintf(w, "Could not render journal: %v", err)
+	}
+}
+
+func (c *Control) Undo(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	c.renderUndo(w, c.Accounts.Undo())
+}
+
+func (c *Control) Redo(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	c.renderUndo(w, c.Accounts.Redo())
+}
+
+func (c *Control) renderUndo(w http.ResponseWriter, err error) {
+	if err == nil {
+		w.Header().Add("HX-Refresh", "true")
+	}
+	c.renderStatus(w, history.FileUnchanged, nil, err)
+}
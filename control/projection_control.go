@@ -0,0 +1,102 @@
+package control
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/jwiklund/ah/history"
+	"github.com/jwiklund/ah/money"
+)
+
+const projectionSimulations = 1000
+
+type Projection struct {
+	Currency     string
+	Tag          string
+	Real         string
+	Years        []history.SummaryEntry
+	Projection   []history.ProjectionEntry
+	Return       float64
+	Volatility   float64
+	Contribution money.Amount
+	Horizon      int
+	Seed         uint64
+	Error        error
+}
+
+func (c *Control) Projection(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	opts := viewOptions(r, history.Yearly)
+	opts.Granularity = history.Yearly
+	data := newProjection(c.Accounts, opts)
+	data.Error = parseProjection(r, &data)
+	if data.Error == nil && len(data.Years) > 0 {
+		last := data.Years[len(data.Years)-1]
+		data.Projection, data.Error = data.project(last)
+	}
+	if err := c.Renderer.Render(templateName("projection", r), w, data); err != nil {
+		fmt.Fprintf(w, "Could not render projection: %v", err)
+	}
+}
+
+func newProjection(a *history.Accounts, opts history.ViewOptions) Projection {
+	data := Projection{
+		Currency:   a.Currency(),
+		Tag:        opts.Tag,
+		Real:       opts.Real,
+		Return:     5,
+		Volatility: 15,
+		Horizon:    20,
+		Seed:       1,
+	}
+	data.Years, _ = a.Summary(opts)
+	if len(data.Years) > 0 {
+		data.Contribution = data.Years[len(data.Years)-1].Change
+	}
+	return data
+}
+
+func (p Projection) project(from history.SummaryEntry) ([]history.ProjectionEntry, error) {
+	return history.Project(from.End, from.Year, history.ProjectionOptions{
+		Return:       p.Return / 100,
+		Volatility:   p.Volatility / 100,
+		Contribution: p.Contribution,
+		Years:        p.Horizon,
+		Simulations:  projectionSimulations,
+	}, rand.New(rand.NewPCG(p.Seed, p.Seed)))
+}
+
+func parseProjection(r *http.Request, data *Projection) error {
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+	var err error
+	if formInput(r, "return") != "" {
+		if data.Return, err = formFloatInput(r, "return"); err != nil {
+			return err
+		}
+	}
+	if formInput(r, "volatility") != "" {
+		if data.Volatility, err = formFloatInput(r, "volatility"); err != nil {
+			return err
+		}
+	}
+	if formInput(r, "contribution") != "" {
+		if data.Contribution, err = formAmountInput(r, "contribution"); err != nil {
+			return err
+		}
+	}
+	if input := formInput(r, "horizon"); input != "" {
+		if data.Horizon, err = strconv.Atoi(input); err != nil {
+			return fmt.Errorf("invalid value for horizon: %v", err)
+		}
+	}
+	if input := formInput(r, "seed"); input != "" {
+		if data.Seed, err = strconv.ParseUint(input, 10, 64); err != nil {
+			return fmt.Errorf("invalid value for seed: %v", err)
+		}
+	}
+	return nil
+}
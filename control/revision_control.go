@@ -0,0 +1,50 @@
+package control
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/jwiklund/ah/history"
+)
+
+type Revisions struct {
+	Revisions []history.Revision
+	Error     error
+}
+
+func (c *Control) Revisions(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	revisions, err := c.Accounts.Revisions()
+	data := Revisions{
+		Revisions: revisions,
+		Error:     err,
+	}
+	if err := c.Renderer.Render(templateName("revisions", r), w, data); err != nil {
+		fmt.Fprintf(w, "Could not render revisions: %v", err)
+	}
+}
+
+type Revision struct {
+	Revision string
+	Currency string
+	Current  []history.CurrentEntry
+	Years    []history.SummaryEntry
+	Error    error
+}
+
+func (c *Control) Revision(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	data := Revision{
+		Revision: p.ByName("revision"),
+	}
+	accounts, err := c.Accounts.AtRevision(data.Revision)
+	if err == nil {
+		opts := viewOptions(r, history.Yearly)
+		data.Currency = accounts.Currency()
+		data.Current = accounts.Current(opts)
+		data.Years, _ = accounts.Summary(opts)
+	}
+	data.Error = err
+	if err := c.Renderer.Render(templateName("revision", r), w, data); err != nil {
+		fmt.Fprintf(w, "Could not render revision: %v", err)
+	}
+}
@@ -0,0 +1,86 @@
+package control
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/jwiklund/ah/history"
+)
+
+type Status struct {
+	Dirty     bool
+	Revision  uint64
+	File      history.FileStatus
+	Error     error
+	CanUndo   bool
+	CanRedo   bool
+	UndoError error
+}
+
+func (c *Control) Status(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	file, err := c.Accounts.CheckFile(c.AccountsPath)
+	if file == history.FileReloaded {
+		w.Header().Add("HX-Refresh", "true")
+	}
+	c.renderStatus(w, file, err, nil)
+}
+
+func (c *Control) renderStatus(w http.ResponseWriter, file history.FileStatus, err, undoErr error) {
+	status := Status{
+		Dirty:     c.Accounts.Dirty(),
+		Revision:  c.Accounts.Revision(),
+		File:      file,
+		Error:     err,
+		CanUndo:   c.Accounts.CanUndo(),
+		CanRedo:   c.Accounts.CanRedo(),
+		UndoError: undoErr,
+	}
+	if err := c.Renderer.Render("status.html", w, status); err != nil {
+		fmt.Fprintf(w, "Could not render status: %v", err)
+	}
+}
+
+func (c *Control) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var last history.FileStatus
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		status, err := c.Accounts.CheckFile(c.AccountsPath)
+		if err != nil {
+			fmt.Printf("Could not check %s: %v\n", c.AccountsPath, err)
+			continue
+		}
+		if status != last && status != history.FileUnchanged {
+			fmt.Printf("%s %s\n", c.AccountsPath, status)
+		}
+		last = status
+	}
+}
+
+func (c *Control) Autosave(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var last uint64
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		revision := c.Accounts.Revision()
+		if revision == last && c.Accounts.Dirty() {
+			if err := c.Accounts.Save(c.AccountsPath, c.KeepBackups); err != nil {
+				fmt.Printf("Could not autosave: %v\n", err)
+			}
+		}
+		last = revision
+	}
+}
@@ -0,0 +1,73 @@
+package control
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/jwiklund/ah/history"
+)
+
+type Tags struct {
+	Tags     []history.TagEntry
+	Accounts []history.CurrentEntry
+	Message  string
+	Error    error
+}
+
+func (c *Control) RenderTags(w http.ResponseWriter, r *http.Request, message string, err error) {
+	tags := Tags{
+		Tags:     c.Accounts.Tags(),
+		Accounts: c.Accounts.Current(history.ViewOptions{Archived: true}),
+		Message:  message,
+		Error:    err,
+	}
+	if err := c.Renderer.Render(templateName("tags", r), w, tags); err != nil {
+		fmt.Fprintf(w, "Could not render tags: %v", err)
+	}
+}
+
+func (c *Control) Tags(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	c.RenderTags(w, r, "", nil)
+}
+
+func (c *Control) TagsAdd(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	err := r.ParseForm()
+	if err != nil {
+		c.RenderTags(w, r, "", err)
+		return
+	}
+	err = c.Accounts.AddTag(formInput(r, "account"), formInput(r, "tag"))
+	c.RenderTags(w, r, "", err)
+}
+
+func (c *Control) TagsRemove(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	err := r.ParseForm()
+	if err != nil {
+		c.RenderTags(w, r, "", err)
+		return
+	}
+	err = c.Accounts.RemoveTag(formInput(r, "account"), rawFormInput(r, "tag"))
+	c.RenderTags(w, r, "", err)
+}
+
+func (c *Control) TagsRename(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	err := r.ParseForm()
+	if err != nil {
+		c.RenderTags(w, r, "", err)
+		return
+	}
+	from := rawFormInput(r, "from")
+	to := formInput(r, "to")
+	var changed int
+	if formInput(r, "prefix") == "true" {
+		changed, err = c.Accounts.RenameTagPrefix(from, to)
+	} else {
+		changed, err = c.Accounts.RenameTag(from, to)
+	}
+	message := ""
+	if err == nil {
+		message = fmt.Sprintf("Renamed %s to %s on %d accounts", from, to, changed)
+	}
+	c.RenderTags(w, r, message, err)
+}
@@ -6,9 +6,10 @@ import (
 	"fmt"
 	"os/exec"
 	"regexp"
-	"strconv"
 	"strings"
 
+	"github.com/jwiklund/ah/history"
+	"github.com/jwiklund/ah/money"
 	"golang.org/x/exp/slices"
 )
 
@@ -38,6 +39,10 @@ const (
 	Date   ImportColumnType = "date"
 	Amount ImportColumnType = "amount"
 	Change ImportColumnType = "change"
+
+	Withdrawal ImportColumnType = "withdrawal"
+	Income     ImportColumnType = "income"
+	Fees       ImportColumnType = "fees"
 )
 
 var ColumnTypes = map[ImportColumnType]string{
@@ -46,6 +51,10 @@ var ColumnTypes = map[ImportColumnType]string{
 	Date:   "Date",
 	Amount: "Amount",
 	Change: "Change",
+
+	Withdrawal: "Withdrawal",
+	Income:     "Income",
+	Fees:       "Fees",
 }
 
 type ImportPlugin struct {
@@ -164,9 +173,9 @@ func importLine(line string, separator *regexp.Regexp, columnFeatures []importCo
 		columns = append(columns, ImportColumn{
 			Value: value,
 		})
-		_, err := strconv.Atoi(value)
+		_, err := money.Parse(value)
 		columnNumeric := err == nil
-		columnDate := err == nil && len(value) == 4
+		columnDate := history.ValidDate(value)
 
 		if len(columnFeatures) <= i {
 			columnFeatures = append(columnFeatures, importColumnFeature{
@@ -278,9 +287,6 @@ func typeColumns(opts ImportOptions, features []importColumnFeature) []ImportCol
 	return result
 }
 
-var datePattern = regexp.MustCompile("\\d{4}")
-var numberPattern = regexp.MustCompile("-?\\d+")
-
 func (t ImportColumnType) Validate(value string) error {
 	switch t {
 	case None:
@@ -291,18 +297,13 @@ func (t ImportColumnType) Validate(value string) error {
 		}
 		return nil
 	case Date:
-		if !datePattern.MatchString(value) {
-			return errors.New("must match YYYY")
-		}
-		return nil
-	case Amount:
-		if !numberPattern.MatchString(value) {
-			return errors.New("must be numeric")
+		if !history.ValidDate(value) {
+			return errors.New("must match YYYY, YYYY-Qn or YYYY-MM")
 		}
 		return nil
-	case Change:
-		if !numberPattern.MatchString(value) {
-			return errors.New("must be numeric")
+	case Amount, Change, Withdrawal, Income, Fees:
+		if _, err := money.Parse(value); err != nil {
+			return fmt.Errorf("must be numeric: %w", err)
 		}
 		return nil
 	default:
@@ -325,7 +326,7 @@ func validateRows(rows []ImportRow, name string, date string) error {
 				hasDate = true
 			case Amount:
 				hasAmount = true
-			case Change:
+			case Change, Withdrawal, Income, Fees:
 				hasChange = true
 			}
 			if column.Error != nil {
@@ -339,7 +340,7 @@ func validateRows(rows []ImportRow, name string, date string) error {
 			return errors.New("Date is required")
 		}
 		if !hasAmount && !hasChange {
-			return errors.New("Valid Amount or Change")
+			return errors.New("Valid Amount, Change, Withdrawal, Income or Fees")
 		}
 		if hasError {
 			return errors.New("Invalid entry")
@@ -1,19 +1,24 @@
 package csv
 
 import (
-	"strconv"
-	"strings"
-
 	"github.com/jwiklund/ah/history"
+	"github.com/jwiklund/ah/money"
 	"golang.org/x/exp/slices"
 )
 
 type historyUpdate struct {
 	date      string
-	amount    int
+	amount    money.Amount
 	hasAmount bool
-	change    int
+	change    money.Amount
 	hasChange bool
+
+	withdrawal    money.Amount
+	hasWithdrawal bool
+	income        money.Amount
+	hasIncome     bool
+	fees          money.Amount
+	hasFees       bool
 }
 
 type historyUpdates []historyUpdate
@@ -21,7 +26,7 @@ type historyUpdates []historyUpdate
 func (h ImportRows) Update(opts ImportOptions, accounts *history.Accounts) error {
 	for slug, updates := range h.rowsBySlug(opts) {
 		slices.SortFunc(updates, func(a, b historyUpdate) int {
-			return strings.Compare(a.date, b.date)
+			return history.CompareDates(a.date, b.date)
 		})
 		err := accounts.UpdateHistoryBySlug(slug, updates.update)
 		if err != nil {
@@ -46,11 +51,20 @@ func (h ImportRows) rowsBySlug(opts ImportOptions) map[string]historyUpdates {
 			case Date:
 				update.date = column.Value
 			case Amount:
-				update.amount, _ = strconv.Atoi(column.Value)
+				update.amount, _ = money.Parse(column.Value)
 				update.hasAmount = true
 			case Change:
-				update.change, _ = strconv.Atoi(column.Value)
+				update.change, _ = money.Parse(column.Value)
 				update.hasChange = true
+			case Withdrawal:
+				update.withdrawal, _ = money.Parse(column.Value)
+				update.hasWithdrawal = true
+			case Income:
+				update.income, _ = money.Parse(column.Value)
+				update.hasIncome = true
+			case Fees:
+				update.fees, _ = money.Parse(column.Value)
+				update.hasFees = true
 			}
 		}
 
@@ -65,12 +79,12 @@ func (h ImportRows) rowsBySlug(opts ImportOptions) map[string]historyUpdates {
 
 func (u historyUpdates) update(h []history.History) ([]history.History, error) {
 	slices.SortFunc(h, func(a, b history.History) int {
-		return strings.Compare(a.Date, b.Date)
+		return history.CompareDates(a.Date, b.Date)
 	})
 	historyIndex := 0
 	var result []history.History
 	for updateIndex := 0; updateIndex < len(u); {
-		if historyIndex < len(h) && h[historyIndex].Date < u[updateIndex].date {
+		if historyIndex < len(h) && history.CompareDates(h[historyIndex].Date, u[updateIndex].date) < 0 {
 			result = append(result, h[historyIndex])
 			historyIndex++
 			continue
@@ -81,7 +95,7 @@ func (u historyUpdates) update(h []history.History) ([]history.History, error) {
 			updateIndex++
 			continue
 		}
-		if historyIndex >= len(h) || h[historyIndex].Date > u[updateIndex].date {
+		if historyIndex >= len(h) || history.CompareDates(h[historyIndex].Date, u[updateIndex].date) > 0 {
 			result = append(result, updateHistory(
 				history.History{
 					Date: u[updateIndex].date,
@@ -101,5 +115,14 @@ func updateHistory(h history.History, update historyUpdate) history.History {
 	if update.hasChange {
 		h.Change = update.change
 	}
+	if update.hasWithdrawal {
+		h.Withdrawal = update.withdrawal
+	}
+	if update.hasIncome {
+		h.Income = update.income
+	}
+	if update.hasFees {
+		h.Fees = update.fees
+	}
 	return h
 }
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/jwiklund/ah/history"
+	"github.com/jwiklund/ah/money"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -39,20 +40,24 @@ func TestUpdateEmptyWithAccount(t *testing.T) {
 	if err != nil {
 		return
 	}
-	summary, _ := empty.Summary("")
+	summary, _ := empty.Summary(history.ViewOptions{})
 	assert.Equal(t, []history.SummaryEntry{{
 		Year:     "2022",
+		Assets:   money.FromInt(1),
 		Start:    0,
-		End:      1,
+		End:      money.FromInt(1),
 		Change:   0,
-		Increase: 1,
+		Increase: money.FromInt(1),
+		Market:   money.FromInt(1),
 	}}, summary)
 	assert.Equal(t, []history.CurrentEntry{{
+		ID:       "name",
 		Name:     "name",
 		Slug:     "name",
+		Kind:     history.Asset,
 		Start:    0,
-		End:      1,
+		End:      money.FromInt(1),
 		Change:   0,
-		Increase: 1,
-	}}, empty.Current())
+		Increase: money.FromInt(1),
+	}}, empty.Current(history.ViewOptions{}))
 }
@@ -0,0 +1,130 @@
+package history
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	backupTimeFormat  = "20060102T150405.000000000"
+	maxBackupAttempts = 100
+)
+
+type Backup struct {
+	Name string
+	Time time.Time
+	Size int64
+}
+
+func Backups(filename string) ([]Backup, error) {
+	prefix := filepath.Base(filename) + "."
+	entries, err := os.ReadDir(filepath.Dir(filename))
+	if err != nil {
+		return nil, err
+	}
+	var result []Backup
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ".bak") {
+			continue
+		}
+		timestamp := strings.TrimSuffix(strings.TrimPrefix(name, prefix), ".bak")
+		t, err := time.ParseInLocation(backupTimeFormat, timestamp, time.Local)
+		if err != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, Backup{Name: name, Time: t, Size: info.Size()})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Time.After(result[j].Time)
+	})
+	return result, nil
+}
+
+func backup(filename string, keep int) error {
+	if keep <= 0 {
+		return nil
+	}
+	in, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("could not write backup: %w", err)
+	}
+	defer in.Close()
+
+	name, err := backupName(filename)
+	if err != nil {
+		return fmt.Errorf("could not write backup: %w", err)
+	}
+	err = writeAtomic(name, func(out io.Writer) error {
+		_, err := io.Copy(out, in)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("could not write backup: %w", err)
+	}
+
+	backups, err := Backups(filename)
+	if err != nil {
+		return fmt.Errorf("could not list backups: %w", err)
+	}
+	for i := keep; i < len(backups); i++ {
+		if err := os.Remove(filepath.Join(filepath.Dir(filename), backups[i].Name)); err != nil {
+			return fmt.Errorf("could not remove backup: %w", err)
+		}
+	}
+	return nil
+}
+
+func backupName(filename string) (string, error) {
+	for i := 0; i < maxBackupAttempts; i++ {
+		name := fmt.Sprintf("%s.%s.bak", filename, time.Now().Format(backupTimeFormat))
+		_, err := os.Stat(name)
+		if os.IsNotExist(err) {
+			return name, nil
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return "", fmt.Errorf("no free backup name for %s", filename)
+}
+
+func (a *Accounts) Restore(filename string, name string, keep int) error {
+	backups, err := Backups(filename)
+	if err != nil {
+		return err
+	}
+	found := false
+	for _, b := range backups {
+		found = found || b.Name == name
+	}
+	if !found {
+		return fmt.Errorf("No such backup: %s", name)
+	}
+	restored, _, err := loadFile(a.storage, filepath.Join(filepath.Dir(filename), name))
+	if err != nil {
+		return err
+	}
+	if err := backup(filename, keep); err != nil {
+		return err
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.accounts = sortAccounts(restored.accounts, a.tagDefinitionsLocked())
+	a.changedLocked(a.allIDsLocked()...)
+	return a.saveLocked(filename)
+}
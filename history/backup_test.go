@@ -0,0 +1,96 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSaveBackups(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "accounts.txt")
+	accounts := New()
+	for _, name := range []string{"a", "b", "c", "d"} {
+		assert.NoError(t, accounts.AddAccount(name, "2022"))
+		assert.NoError(t, accounts.Save(filename, 2))
+	}
+
+	backups, err := Backups(filename)
+	assert.NoError(t, err)
+	assert.Len(t, backups, 2)
+	assert.True(t, backups[0].Time.After(backups[1].Time))
+
+	entries, err := os.ReadDir(filepath.Dir(filename))
+	assert.NoError(t, err)
+	assert.Len(t, entries, 3)
+
+	loaded, err := Load(filename, false)
+	assert.NoError(t, err)
+	assert.Len(t, loaded.accounts, 4)
+	restored, err := Load(filepath.Join(filepath.Dir(filename), backups[0].Name), false)
+	assert.NoError(t, err)
+	assert.Len(t, restored.accounts, 3)
+}
+
+func TestSaveKeepsMode(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "accounts.txt")
+	accounts := New()
+	assert.NoError(t, accounts.AddAccount("a", "2022"))
+	assert.NoError(t, accounts.Save(filename, 0))
+	assert.NoError(t, os.Chmod(filename, 0o640))
+
+	assert.NoError(t, accounts.AddAccount("b", "2022"))
+	assert.NoError(t, accounts.Save(filename, 0))
+	info, err := os.Stat(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o640), info.Mode().Perm())
+}
+
+func TestBackupNameError(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "file")
+	assert.NoError(t, os.WriteFile(file, nil, 0600))
+	_, err := backupName(filepath.Join(file, "accounts.txt"))
+	assert.Error(t, err)
+}
+
+func TestRestore(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "accounts.txt")
+	accounts := New()
+	assert.NoError(t, accounts.AddAccount("a", "2022"))
+	assert.NoError(t, accounts.Save(filename, 5))
+	assert.NoError(t, accounts.AddAccount("b", "2022"))
+	assert.NoError(t, accounts.Save(filename, 5))
+
+	assert.Error(t, accounts.Restore(filename, "../accounts.txt", 5))
+	backups, err := Backups(filename)
+	assert.NoError(t, err)
+	assert.Len(t, backups, 1)
+	assert.NoError(t, accounts.Restore(filename, backups[0].Name, 5))
+	assert.Len(t, accounts.accounts, 1)
+
+	loaded, err := Load(filename, false)
+	assert.NoError(t, err)
+	assert.Len(t, loaded.accounts, 1)
+	backups, err = Backups(filename)
+	assert.NoError(t, err)
+	assert.Len(t, backups, 2)
+}
+
+func TestDirty(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "accounts.txt")
+	accounts := New()
+	assert.False(t, accounts.Dirty())
+	assert.NoError(t, accounts.AddAccount("a", "2022"))
+	assert.NoError(t, accounts.UpdateAmount("a", "2022", 10))
+	assert.True(t, accounts.Dirty())
+	assert.Equal(t, uint64(2), accounts.Revision())
+
+	assert.Error(t, accounts.UpdateAmount("missing", "2022", 10))
+	assert.Equal(t, uint64(2), accounts.Revision())
+
+	assert.NoError(t, accounts.Save(filename, 0))
+	assert.False(t, accounts.Dirty())
+	assert.NoError(t, accounts.AddDate("2023"))
+	assert.True(t, accounts.Dirty())
+}
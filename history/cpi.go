@@ -0,0 +1,44 @@
+package history
+
+func (a *Accounts) SetCPI(cpi *Table) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.cpi = cpi
+}
+
+// deflateLocked counts the loss of value from inflation as part of the increase.
+func (a *Accounts) deflateLocked(entries []SummaryEntry, currency, base string, kind Kind) {
+	if base == "" || !a.cpi.Has(currency) {
+		return
+	}
+	baseIndex, _ := a.cpi.Lookup(currency, RollUp(base, Yearly))
+	account := Account{Kind: kind}
+	previous := 1.0
+	for i := range entries {
+		entry := &entries[i]
+		factor := previous
+		if index, _ := a.cpi.Lookup(currency, RollUp(entry.Year, Yearly)); index > 0 {
+			factor = baseIndex / index
+		}
+		if i == 0 {
+			previous = factor
+		}
+		entry.Start = entry.Start.Mul(previous)
+		entry.End = entry.End.Mul(factor)
+		entry.Assets = entry.Assets.Mul(factor)
+		entry.Liabilities = entry.Liabilities.Mul(factor)
+		entry.Illiquid = entry.Illiquid.Mul(factor)
+		entry.Change = entry.Change.Mul(factor)
+		entry.Contributions = entry.Contributions.Mul(factor)
+		entry.Withdrawals = entry.Withdrawals.Mul(factor)
+		entry.Transfers = entry.Transfers.Mul(factor)
+		entry.Income = entry.Income.Mul(factor)
+		entry.Fees = entry.Fees.Mul(factor)
+		entry.Oneoff = entry.Oneoff.Mul(factor)
+		entry.FX = entry.FX.Mul(factor)
+		entry.Increase = account.value(entry.End-entry.Start) - entry.Change - entry.Transfers - entry.Oneoff - entry.FX
+		entry.Market = entry.Increase - entry.Income + entry.Fees
+		previous = factor
+	}
+}
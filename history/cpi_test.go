@@ -0,0 +1,60 @@
+package history
+
+import (
+	"testing"
+
+	"github.com/jwiklund/ah/money"
+	"github.com/stretchr/testify/assert"
+)
+
+const cpiExample = `
+name: isk
+history:
+- date: "2022"
+  amount: 100
+  change: 100
+- date: "2023"
+  amount: 105
+`
+
+func cpiAccounts(t *testing.T) *Accounts {
+	accounts := testAccounts(t, cpiExample)
+	rates := NewTable()
+	assert.NoError(t, rates.Add("EUR", "2022", 10))
+	assert.NoError(t, accounts.SetCurrency("SEK", rates))
+	cpi := NewTable()
+	assert.NoError(t, cpi.Add("SEK", "2022", 100))
+	assert.NoError(t, cpi.Add("SEK", "2023", 110))
+	accounts.SetCPI(cpi)
+	return accounts
+}
+
+func TestSummaryReal(t *testing.T) {
+	accounts := cpiAccounts(t)
+
+	summary, _ := accounts.Summary(ViewOptions{Real: "2023"})
+	assert.Equal(t, []SummaryEntry{
+		{Year: "2022", Assets: money.FromInt(110), End: money.FromInt(110), Change: money.FromInt(110), Contributions: money.FromInt(110)},
+		{Year: "2023", Assets: money.FromInt(105), Start: money.FromInt(110), End: money.FromInt(105), Increase: money.FromInt(-5), Market: money.FromInt(-5)},
+	}, summary)
+
+	nominal, _ := accounts.Summary(ViewOptions{})
+	assert.Equal(t, money.FromInt(5), nominal[1].Increase)
+
+	returns := accounts.SummaryReturns(ViewOptions{Real: "2023"})
+	assert.InDelta(t, -5.0/110, returns[1].Return, 1e-9)
+}
+
+func TestAccountHistoryReal(t *testing.T) {
+	accounts := cpiAccounts(t)
+
+	_, history, err := accounts.AccountHistory("isk", ViewOptions{Real: "2022"})
+	assert.NoError(t, err)
+	end := money.FromInt(105).Mul(100.0 / 110)
+	assert.Equal(t, SummaryEntry{Year: "2023", Start: money.FromInt(100), End: end, Increase: end - money.FromInt(100), Market: end - money.FromInt(100)}, history[1])
+
+	assert.NoError(t, accounts.UpdateCurrency("isk", "EUR"))
+	_, history, err = accounts.AccountHistory("isk", ViewOptions{Real: "2022", Native: true})
+	assert.NoError(t, err)
+	assert.Equal(t, money.FromInt(105), history[1].End)
+}
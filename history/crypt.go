@@ -0,0 +1,161 @@
+package history
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// Encrypted files start with encryptedMagic, the scrypt salt and the AES-GCM nonce.
+var encryptedMagic = []byte("AHENC1\n")
+
+const (
+	saltSize = 16
+	keySize  = 32
+)
+
+var ErrPassphrase = errors.New("wrong passphrase or corrupted file")
+
+type Key struct {
+	passphrase []byte
+}
+
+func NewKey(passphrase string) (*Key, error) {
+	if passphrase == "" {
+		return nil, fmt.Errorf("Empty passphrase")
+	}
+	return &Key{passphrase: []byte(passphrase)}, nil
+}
+
+func IsEncrypted(filename string) (bool, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+	header := make([]byte, len(encryptedMagic))
+	n, _ := file.Read(header)
+	return isEncrypted(header[:n]), nil
+}
+
+func EncryptFile(filename string, key *Key) error {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	if isEncrypted(content) {
+		return fmt.Errorf("%s is already encrypted", filename)
+	}
+	if content, err = key.encrypt(content); err != nil {
+		return err
+	}
+	return writeAtomic(filename, func(writer io.Writer) error {
+		_, err := writer.Write(content)
+		return err
+	})
+}
+
+func EncryptBackups(filename string, key *Key) (int, error) {
+	backups, err := Backups(filename)
+	if err != nil {
+		return 0, err
+	}
+	encrypted := 0
+	for _, backup := range backups {
+		name := filepath.Join(filepath.Dir(filename), backup.Name)
+		done, err := IsEncrypted(name)
+		if err != nil {
+			return encrypted, err
+		}
+		if done {
+			continue
+		}
+		if err := EncryptFile(name, key); err != nil {
+			return encrypted, err
+		}
+		encrypted++
+	}
+	return encrypted, nil
+}
+
+func DecryptFile(filename string, key *Key) error {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	if !isEncrypted(content) {
+		return fmt.Errorf("%s is not encrypted", filename)
+	}
+	if content, err = key.decrypt(content); err != nil {
+		return err
+	}
+	return writeAtomic(filename, func(writer io.Writer) error {
+		_, err := writer.Write(content)
+		return err
+	})
+}
+
+func isEncrypted(content []byte) bool {
+	return bytes.HasPrefix(content, encryptedMagic)
+}
+
+func (k *Key) aead(salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key(k.passphrase, salt, 1<<15, 8, 1, keySize)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (k *Key) encrypt(plain []byte) ([]byte, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	aead, err := k.aead(salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	header := append(append(append([]byte{}, encryptedMagic...), salt...), nonce...)
+	return aead.Seal(header, nonce, plain, header), nil
+}
+
+func (k *Key) decrypt(content []byte) ([]byte, error) {
+	if !isEncrypted(content) {
+		return nil, fmt.Errorf("not an encrypted file")
+	}
+	salt := content[len(encryptedMagic):min(len(content), len(encryptedMagic)+saltSize)]
+	if len(salt) != saltSize {
+		return nil, ErrPassphrase
+	}
+	aead, err := k.aead(salt)
+	if err != nil {
+		return nil, err
+	}
+	headerSize := len(encryptedMagic) + saltSize + aead.NonceSize()
+	if len(content) < headerSize {
+		return nil, ErrPassphrase
+	}
+	header := content[:headerSize]
+	plain, err := aead.Open(nil, header[len(header)-aead.NonceSize():], content[headerSize:], header)
+	if err != nil {
+		return nil, ErrPassphrase
+	}
+	return plain, nil
+}
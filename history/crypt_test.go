@@ -0,0 +1,105 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	key, err := NewKey("secret")
+	assert.NoError(t, err)
+	encrypted, err := key.encrypt([]byte("net worth"))
+	assert.NoError(t, err)
+	assert.True(t, isEncrypted(encrypted))
+	assert.NotContains(t, string(encrypted), "net worth")
+
+	plain, err := key.decrypt(encrypted)
+	assert.NoError(t, err)
+	assert.Equal(t, "net worth", string(plain))
+
+	wrong, _ := NewKey("wrong")
+	_, err = wrong.decrypt(encrypted)
+	assert.ErrorIs(t, err, ErrPassphrase)
+	encrypted[len(encrypted)-1] ^= 1
+	_, err = key.decrypt(encrypted)
+	assert.ErrorIs(t, err, ErrPassphrase)
+	_, err = key.decrypt(encryptedMagic)
+	assert.ErrorIs(t, err, ErrPassphrase)
+
+	_, err = NewKey("")
+	assert.Error(t, err)
+}
+
+func TestEncryptFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "accounts.txt")
+	plain := "# savings\nname: bank\nhistory:\n  - date: \"2022\"\n    amount: 1\n"
+	assert.NoError(t, os.WriteFile(filename, []byte(plain), 0644))
+	key, _ := NewKey("secret")
+	assert.EqualError(t, DecryptFile(filename, key), filename+" is not encrypted")
+	assert.NoError(t, EncryptFile(filename, key))
+	assert.EqualError(t, EncryptFile(filename, key), filename+" is already encrypted")
+	wrong, _ := NewKey("wrong")
+	assert.ErrorIs(t, DecryptFile(filename, wrong), ErrPassphrase)
+	assert.NoError(t, DecryptFile(filename, key))
+	content, _ := os.ReadFile(filename)
+	assert.Equal(t, plain, string(content))
+}
+
+func TestEncryptBackups(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "accounts.txt")
+	accounts := transferAccounts(t)
+	assert.NoError(t, accounts.Save(filename, 2))
+	assert.NoError(t, accounts.UpdateAmount("bank", "2023", 80))
+	assert.NoError(t, accounts.Save(filename, 2))
+	key, _ := NewKey("secret")
+
+	encrypted, err := EncryptBackups(filename, key)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, encrypted)
+	backups, _ := Backups(filename)
+	done, _ := IsEncrypted(filepath.Join(filepath.Dir(filename), backups[0].Name))
+	assert.True(t, done)
+	encrypted, err = EncryptBackups(filename, key)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, encrypted)
+}
+
+func TestEncryptedStorage(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "accounts.txt")
+	accounts := transferAccounts(t)
+	assert.NoError(t, accounts.Save(filename, 1))
+	encrypted, err := IsEncrypted(filename)
+	assert.NoError(t, err)
+	assert.False(t, encrypted)
+
+	key, _ := NewKey("secret")
+	plain, err := Open(YAML{Key: key}, filename, false)
+	assert.NoError(t, err)
+	assert.NoError(t, plain.Save(filename, 1))
+	encrypted, err = IsEncrypted(filename)
+	assert.NoError(t, err)
+	assert.True(t, encrypted)
+
+	_, err = Load(filename, false)
+	assert.EqualError(t, err, "could not read "+filename+": "+filename+" is encrypted and no passphrase was given")
+	wrong, _ := NewKey("wrong")
+	_, err = Open(YAML{Key: wrong}, filename, false)
+	assert.ErrorIs(t, err, ErrPassphrase)
+
+	loaded, err := Open(YAML{Key: key}, filename, false)
+	assert.NoError(t, err)
+	assert.True(t, sameYAML(accounts.accounts, loaded.accounts))
+
+	assert.NoError(t, loaded.Save(filename, 1))
+	backups, err := Backups(filename)
+	assert.NoError(t, err)
+	content, err := os.ReadFile(filepath.Join(filepath.Dir(filename), backups[0].Name))
+	assert.NoError(t, err)
+	assert.True(t, isEncrypted(content))
+
+	_, err = StorageFor("sqlite", key)
+	assert.Error(t, err)
+}
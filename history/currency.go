@@ -0,0 +1,58 @@
+package history
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+func (a *Accounts) SetCurrency(currency string, rates *Table) error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.currency = currency
+	a.rates = rates
+	missing := make(map[string]bool)
+	for _, account := range a.accounts {
+		if account.Currency != "" && account.Currency != currency && !rates.Has(account.Currency) {
+			missing[account.Currency] = true
+		}
+	}
+	if len(missing) > 0 {
+		var currencies []string
+		for currency := range missing {
+			currencies = append(currencies, currency)
+		}
+		sort.Strings(currencies)
+		return fmt.Errorf("No exchange rates for %s", strings.Join(currencies, ", "))
+	}
+	return nil
+}
+
+func (a *Accounts) Currency() string {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	return a.currency
+}
+
+func (a *Accounts) currencyLocked(account Account) string {
+	if account.Currency == "" {
+		return a.currency
+	}
+	return account.Currency
+}
+
+func (a *Accounts) rateLocked(currency, date string) (float64, error) {
+	if currency == "" || currency == a.currency {
+		return 1, nil
+	}
+	if !a.rates.Has(currency) {
+		return 0, fmt.Errorf("No exchange rates for %s", currency)
+	}
+	rate, ok := a.rates.Lookup(currency, date)
+	if !ok {
+		return 0, fmt.Errorf("No exchange rate for %s at %s", currency, date)
+	}
+	return rate, nil
+}
@@ -0,0 +1,77 @@
+package history
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+
+	"github.com/jwiklund/ah/money"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadTableCsv(t *testing.T) {
+	table, err := LoadTableCsv(bytes.NewBufferString("date,currency,rate\n2022,EUR,11\n2023,EUR,12\n2022-06,USD,10\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"EUR", "USD"}, table.Keys())
+	rate, ok := table.Lookup("EUR", "2023-03")
+	assert.True(t, ok)
+	assert.Equal(t, 11.0, rate)
+	rate, ok = table.Lookup("EUR", "2023")
+	assert.True(t, ok)
+	assert.Equal(t, 12.0, rate)
+	_, ok = table.Lookup("EUR", "2021")
+	assert.False(t, ok)
+	_, ok = table.Lookup("EUR", "2024-01")
+	assert.False(t, ok)
+	_, ok = table.Lookup("GBP", "2024")
+	assert.False(t, ok)
+}
+
+func TestSummaryCurrency(t *testing.T) {
+	rates, err := LoadTableYaml(bytes.NewBufferString("EUR:\n  \"2022\": 10\n  \"2023\": 12\n"))
+	assert.NoError(t, err)
+	accounts := &Accounts{
+		lock: &sync.Mutex{},
+		accounts: []Account{
+			{
+				ID:       "euro",
+				Name:     "euro",
+				Currency: "EUR",
+				History: []History{
+					{Date: "2022", Amount: money.FromInt(100), Change: money.FromInt(100)},
+					{Date: "2023", Amount: money.FromInt(110), Change: 0},
+				},
+			},
+			{
+				ID:   "krona",
+				Name: "krona",
+				History: []History{
+					{Date: "2022", Amount: money.FromInt(100), Change: money.FromInt(100)},
+					{Date: "2023", Amount: money.FromInt(100), Change: 0},
+				},
+			},
+		},
+	}
+	assert.NoError(t, accounts.SetCurrency("SEK", rates))
+	summary, _ := accounts.Summary(ViewOptions{})
+	assert.Equal(t, []SummaryEntry{
+		{Year: "2022", Assets: money.FromInt(1100), Start: 0, End: money.FromInt(1100), Change: money.FromInt(1100), Contributions: money.FromInt(1100), Increase: 0, Market: 0},
+		{Year: "2023", Assets: money.FromInt(1420), Start: money.FromInt(1100), End: money.FromInt(1420), FX: money.FromInt(200), Increase: money.FromInt(120), Market: money.FromInt(120)},
+	}, summary)
+
+	_, native, err := accounts.AccountHistory("euro", ViewOptions{Native: true})
+	assert.NoError(t, err)
+	assert.Equal(t, money.FromInt(10), native[1].Increase)
+	assert.Equal(t, money.Amount(0), native[1].FX)
+
+	assert.Error(t, accounts.UpdateCurrency("krona", "GBP"))
+	assert.NoError(t, accounts.UpdateCurrency("krona", "EUR"))
+	assert.Error(t, accounts.SetCurrency("SEK", nil))
+
+	summary, _ = accounts.Summary(ViewOptions{})
+	assert.Equal(t, money.Amount(0), summary[1].End)
+	_, _, err = accounts.AccountHistory("euro", ViewOptions{})
+	assert.EqualError(t, err, "No exchange rates for EUR")
+	_, err = accounts.Transfer("euro", "krona", "2023", money.FromInt(1))
+	assert.Error(t, err)
+}
@@ -0,0 +1,259 @@
+package history
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/exp/slices"
+	"gopkg.in/yaml.v3"
+)
+
+var revisionPattern = regexp.MustCompile("^[0-9a-f]{4,40}$")
+
+type Repository struct {
+	dir  string
+	path string
+}
+
+type Revision struct {
+	Hash    string
+	Time    time.Time
+	Message string
+}
+
+func OpenRepository(filename string) (*Repository, error) {
+	dir, err := filepath.Abs(filepath.Dir(filename))
+	if err != nil {
+		return nil, err
+	}
+	if dir, err = filepath.EvalSymlinks(dir); err != nil {
+		return nil, err
+	}
+	top, err := git(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		if _, err := git(dir, "init", "--quiet"); err != nil {
+			return nil, err
+		}
+		if _, err := git(dir, "config", "user.name", "account-history"); err != nil {
+			return nil, err
+		}
+		if _, err := git(dir, "config", "user.email", "account-history@localhost"); err != nil {
+			return nil, err
+		}
+		top = dir
+	}
+	path, err := filepath.Rel(strings.TrimSpace(top), filepath.Join(dir, filepath.Base(filename)))
+	if err != nil {
+		return nil, err
+	}
+	return &Repository{dir: strings.TrimSpace(top), path: filepath.ToSlash(path)}, nil
+}
+
+func Committed(filename string) bool {
+	dir, err := filepath.Abs(filepath.Dir(filename))
+	if err != nil {
+		return false
+	}
+	out, err := git(dir, "log", "--format=%h", "-1", "--", filepath.Base(filename))
+	return err == nil && strings.TrimSpace(out) != ""
+}
+
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s failed: %s", args[0], strings.TrimSpace(stderr.String()))
+	}
+	return string(out), nil
+}
+
+func (r *Repository) git(args ...string) (string, error) {
+	return git(r.dir, args...)
+}
+
+func (r *Repository) Commit(message string) error {
+	if _, err := r.git("add", "--", r.path); err != nil {
+		return err
+	}
+	status, err := r.git("status", "--porcelain", "--", r.path)
+	if err != nil || status == "" {
+		return err
+	}
+	_, err = r.git("commit", "--quiet", "-m", message, "--", r.path)
+	return err
+}
+
+func (r *Repository) Revisions() ([]Revision, error) {
+	if _, err := r.git("rev-parse", "--verify", "--quiet", "HEAD"); err != nil {
+		return nil, nil
+	}
+	out, err := r.git("log", "--format=%H%x1f%ct%x1f%s", "--", r.path)
+	if err != nil {
+		return nil, err
+	}
+	var result []Revision
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		fields := strings.Split(line, "\x1f")
+		if len(fields) != 3 {
+			continue
+		}
+		seconds, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, Revision{Hash: fields[0], Time: time.Unix(seconds, 0), Message: fields[2]})
+	}
+	return result, nil
+}
+
+func (r *Repository) read(storage Storage, revision string) ([]Account, error) {
+	if revision != "HEAD" && !revisionPattern.MatchString(revision) {
+		return nil, fmt.Errorf("No such revision: %s", revision)
+	}
+	object := revision + ":" + r.path
+	if _, err := r.git("cat-file", "-e", object); err != nil {
+		if revision == "HEAD" {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("No such revision: %s", revision)
+	}
+	content, err := r.git("show", object)
+	if err != nil {
+		return nil, err
+	}
+	temp, err := os.CreateTemp("", "account-history-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(temp.Name())
+	_, err = temp.WriteString(content)
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return storage.Read(temp.Name())
+}
+
+func (a *Accounts) SetRepository(repository *Repository) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.repository = repository
+}
+
+func (a *Accounts) Revisions() ([]Revision, error) {
+	a.lock.Lock()
+	repository := a.repository
+	a.lock.Unlock()
+
+	if repository == nil {
+		return nil, fmt.Errorf("Git history is not enabled")
+	}
+	return repository.Revisions()
+}
+
+func (a *Accounts) AtRevision(revision string) (*Accounts, error) {
+	a.lock.Lock()
+	repository, storage := a.repository, a.storage
+	currency, rates, tags := a.currency, a.rates, a.tags
+	a.lock.Unlock()
+
+	if repository == nil {
+		return nil, fmt.Errorf("Git history is not enabled")
+	}
+	accounts, err := repository.read(storage, revision)
+	if err != nil {
+		return nil, err
+	}
+	result := newAccounts(accounts)
+	result.currency, result.rates, result.tags = currency, rates, tags
+	result.accounts = sortAccounts(result.accounts, result.tagDefinitionsLocked())
+	return result, nil
+}
+
+func (a *Accounts) commitLocked(previous []Account) error {
+	if a.repository == nil {
+		return nil
+	}
+	return a.repository.Commit(changeSummary(previous, a.accounts))
+}
+
+// changeSummary is e.g. "Update bank (2022, 2023), house; add fund; remove loan".
+func changeSummary(before, after []Account) string {
+	var updated, added, removed []string
+	for _, account := range after {
+		previous, ok := findAccount(before, account.ID)
+		if !ok {
+			added = append(added, account.Name)
+			continue
+		}
+		var dates []string
+		for _, entry := range account.History {
+			index := historyIndex(previous.History, entry.Date)
+			if index == -1 || !sameYAML(previous.History[index], entry) {
+				dates = append(dates, entry.Date)
+			}
+		}
+		for _, entry := range previous.History {
+			if historyIndex(account.History, entry.Date) == -1 {
+				dates = append(dates, entry.Date)
+			}
+		}
+		slices.SortFunc(dates, CompareDates)
+		switch {
+		case len(dates) > 0:
+			updated = append(updated, fmt.Sprintf("%s (%s)", account.Name, strings.Join(dates, ", ")))
+		case !sameYAML(previous, account):
+			updated = append(updated, account.Name)
+		}
+	}
+	for _, account := range before {
+		if _, ok := findAccount(after, account.ID); !ok {
+			removed = append(removed, account.Name)
+		}
+	}
+
+	var parts []string
+	for _, part := range []struct {
+		verb  string
+		names []string
+	}{{"update", updated}, {"add", added}, {"remove", removed}} {
+		if len(part.names) > 0 {
+			parts = append(parts, part.verb+" "+strings.Join(part.names, ", "))
+		}
+	}
+	if len(parts) == 0 {
+		return "Save"
+	}
+	message := strings.Join(parts, "; ")
+	return strings.ToUpper(message[:1]) + message[1:]
+}
+
+func historyIndex(history []History, date string) int {
+	for i, entry := range history {
+		if entry.Date == date {
+			return i
+		}
+	}
+	return -1
+}
+
+func sameYAML(a, b any) bool {
+	first, err := yaml.Marshal(a)
+	if err != nil {
+		return false
+	}
+	second, err := yaml.Marshal(b)
+	return err == nil && bytes.Equal(first, second)
+}
@@ -0,0 +1,59 @@
+package history
+
+import (
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/jwiklund/ah/money"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGitRevisions(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	filename := filepath.Join(t.TempDir(), "accounts.txt")
+	repository, err := OpenRepository(filename)
+	assert.NoError(t, err)
+	accounts := transferAccounts(t)
+	accounts.SetRepository(repository)
+	revisions, err := accounts.Revisions()
+	assert.NoError(t, err)
+	assert.Empty(t, revisions)
+	assert.False(t, Committed(filename))
+
+	assert.NoError(t, accounts.Save(filename, 0))
+	assert.True(t, Committed(filename))
+	assert.NoError(t, accounts.UpdateAmount("bank", "2023", money.FromInt(80)))
+	assert.NoError(t, accounts.Save(filename, 0))
+	assert.NoError(t, accounts.Save(filename, 0))
+
+	revisions, err = accounts.Revisions()
+	assert.NoError(t, err)
+	assert.Len(t, revisions, 2)
+	assert.Equal(t, "Update bank (2023)", revisions[0].Message)
+	assert.Equal(t, "Add bank, broker", revisions[1].Message)
+
+	old, err := accounts.AtRevision(revisions[1].Hash)
+	assert.NoError(t, err)
+	account, _ := old.Account("bank")
+	assert.Equal(t, money.FromInt(70), account.History[1].Amount)
+	_, err = accounts.AtRevision("--help")
+	assert.EqualError(t, err, "No such revision: --help")
+}
+
+func TestChangeSummary(t *testing.T) {
+	before := []Account{
+		{ID: "bank", Name: "bank", History: []History{{Date: "2022"}, {Date: "2023"}}},
+		{ID: "house", Name: "house"},
+		{ID: "loan", Name: "loan"},
+	}
+	after := []Account{
+		{ID: "bank", Name: "bank", History: []History{{Date: "2022", Amount: 1}, {Date: "2024"}}},
+		{ID: "house", Name: "house", Tags: []string{"property"}},
+		{ID: "fund", Name: "fund"},
+	}
+	assert.Equal(t, "Update bank (2022, 2023, 2024), house; add fund; remove loan", changeSummary(before, after))
+	assert.Equal(t, "Save", changeSummary(after, after))
+}
@@ -0,0 +1,207 @@
+package history
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jwiklund/ah/money"
+	"golang.org/x/exp/slices"
+)
+
+type Holding struct {
+	Security string  `yaml:"security"`
+	Units    float64 `yaml:"units"`
+	Price    float64 `yaml:"price"`
+}
+
+func (h Holding) Value() money.Amount {
+	return money.FromFloat(h.Units * h.Price)
+}
+
+func (h History) withHoldings() History {
+	if len(h.Holdings) == 0 {
+		return h
+	}
+	var amount money.Amount
+	for _, holding := range h.Holdings {
+		amount = amount + holding.Value()
+	}
+	h.Amount = amount
+	return h
+}
+
+func (a *Accounts) UpdateHolding(id string, date string, holding Holding) error {
+	holding.Security = strings.TrimSpace(holding.Security)
+	if holding.Security == "" {
+		return errors.New("Security must not be empty")
+	}
+	return a.UpdateHistory(id, func(history []History) ([]History, error) {
+		index := slices.IndexFunc(history, func(h History) bool { return h.Date == date })
+		if index == -1 {
+			entry := History{Date: date}
+			if previous := previousEntry(history, date); previous != nil {
+				entry.Holdings = slices.Clone(previous.Holdings)
+			}
+			history = append(history, entry)
+			index = len(history) - 1
+		}
+		entry := history[index]
+		entry.Holdings = setHolding(slices.Clone(entry.Holdings), holding)
+		history[index] = entry
+		return history, nil
+	})
+}
+
+func setHolding(holdings []Holding, holding Holding) []Holding {
+	index := slices.IndexFunc(holdings, func(h Holding) bool { return h.Security == holding.Security })
+	switch {
+	case index == -1 && holding.Units != 0:
+		holdings = append(holdings, holding)
+	case index != -1 && holding.Units == 0:
+		holdings = slices.Delete(holdings, index, index+1)
+	case index != -1:
+		holdings[index] = holding
+	}
+	sort.Slice(holdings, func(i, j int) bool {
+		return holdings[i].Security < holdings[j].Security
+	})
+	return holdings
+}
+
+func previousEntry(history []History, date string) *History {
+	var previous *History
+	for i := range history {
+		if CompareDates(history[i].Date, date) < 0 && len(history[i].Holdings) > 0 {
+			previous = &history[i]
+		}
+	}
+	return previous
+}
+
+func (a *Accounts) Revalue(date string, prices *Table) error {
+	if err := ValidateDate(date); err != nil {
+		return err
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	indexes := make([]int, len(a.accounts))
+	for i := range a.accounts {
+		indexes[i] = i
+	}
+	return a.revalueLocked(indexes, date, prices)
+}
+
+func (a *Accounts) RevalueAccount(id string, date string, prices *Table) error {
+	if err := ValidateDate(date); err != nil {
+		return err
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	index, err := a.indexLocked(id)
+	if err != nil {
+		return err
+	}
+	return a.revalueLocked([]int{index}, date, prices)
+}
+
+func (a *Accounts) revalueLocked(indexes []int, date string, prices *Table) error {
+	missing := make(map[string]bool)
+	revalued := make(map[int][]History)
+	for _, i := range indexes {
+		account := a.accounts[i]
+		if account.closedBefore(date) {
+			continue
+		}
+		history := slices.Clone(account.History)
+		index := slices.IndexFunc(history, func(h History) bool { return h.Date == date })
+		if index == -1 || len(history[index].Holdings) == 0 {
+			previous := previousEntry(history, date)
+			if previous == nil {
+				continue
+			}
+			holdings := previous.Holdings
+			if index == -1 {
+				history = append(history, History{Date: date})
+				index = len(history) - 1
+			}
+			history[index].Holdings = holdings
+		}
+		holdings := slices.Clone(history[index].Holdings)
+		for j, holding := range holdings {
+			price, ok := prices.Lookup(holding.Security, date)
+			if !ok {
+				missing[holding.Security] = true
+				continue
+			}
+			holdings[j].Price = price
+		}
+		history[index].Holdings = holdings
+		history[index] = history[index].withHoldings()
+		sortHistory(history)
+		revalued[i] = history
+	}
+	if len(missing) > 0 {
+		var securities []string
+		for security := range missing {
+			securities = append(securities, security)
+		}
+		sort.Strings(securities)
+		return fmt.Errorf("No prices for %s", strings.Join(securities, ", "))
+	}
+	var changed []string
+	for i, history := range revalued {
+		a.accounts[i].History = history
+		changed = append(changed, a.accounts[i].ID)
+	}
+	if len(changed) > 0 {
+		a.changedLocked(changed...)
+	}
+	return nil
+}
+
+type HoldingEntry struct {
+	Year     string
+	Security string
+	Units    float64
+	Price    float64
+	Value    money.Amount
+	Increase money.Amount
+}
+
+// The increase of a security is the price movement of the units held at the start.
+func (a *Accounts) Holdings(id string, opts ViewOptions) ([]HoldingEntry, error) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	index, err := a.indexLocked(id)
+	if err != nil {
+		return nil, err
+	}
+	var result []HoldingEntry
+	previous := make(map[string]Holding)
+	for _, h := range rollUpHistory(a.accounts[index].History, opts.Granularity) {
+		current := make(map[string]Holding)
+		for _, holding := range h.Holdings {
+			entry := HoldingEntry{
+				Year:     h.Date,
+				Security: holding.Security,
+				Units:    holding.Units,
+				Price:    holding.Price,
+				Value:    holding.Value(),
+			}
+			if before, ok := previous[holding.Security]; ok {
+				entry.Increase = money.FromFloat(before.Units * (holding.Price - before.Price))
+			}
+			result = append(result, entry)
+			current[holding.Security] = holding
+		}
+		previous = current
+	}
+	return result, nil
+}
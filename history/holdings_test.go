@@ -0,0 +1,110 @@
+package history
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jwiklund/ah/money"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadFromHoldings(t *testing.T) {
+	accounts, err := LoadFrom(strings.NewReader(`
+name: fund
+history:
+  - date: "2022"
+    amount: 1
+    change: 0
+    holdings:
+      - security: ABC
+        units: 10
+        price: 12.5
+      - security: DEF
+        units: 2
+        price: 100
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, money.FromInt(325), accounts.accounts[0].History[0].Amount)
+}
+
+func TestUpdateHolding(t *testing.T) {
+	accounts := New()
+	assert.NoError(t, accounts.AddEmptyAccount("fund"))
+	assert.Error(t, accounts.UpdateHolding("fund", "2022", Holding{Units: 1, Price: 1}))
+	assert.NoError(t, accounts.UpdateHolding("fund", "2022", Holding{Security: "ABC", Units: 10, Price: 10}))
+	assert.NoError(t, accounts.UpdateHolding("fund", "2023", Holding{Security: "DEF", Units: 1, Price: 50}))
+
+	account, _ := accounts.Account("fund")
+	assert.Equal(t, []History{
+		{Date: "2022", Amount: money.FromInt(100), Holdings: []Holding{{"ABC", 10, 10}}},
+		{Date: "2023", Amount: money.FromInt(150), Holdings: []Holding{{"ABC", 10, 10}, {"DEF", 1, 50}}},
+	}, account.History)
+
+	assert.NoError(t, accounts.UpdateHolding("fund", "2023", Holding{Security: "ABC"}))
+	account, _ = accounts.Account("fund")
+	assert.Equal(t, History{Date: "2023", Amount: money.FromInt(50), Holdings: []Holding{{"DEF", 1, 50}}}, account.History[1])
+}
+
+func TestRevalue(t *testing.T) {
+	accounts := New()
+	assert.NoError(t, accounts.AddEmptyAccount("fund"))
+	assert.NoError(t, accounts.AddAccount("cash", "2022"))
+	assert.NoError(t, accounts.UpdateHolding("fund", "2022", Holding{Security: "ABC", Units: 10, Price: 10}))
+	prices := NewTable()
+	assert.NoError(t, prices.Add("ABC", "2023", 12))
+
+	assert.NoError(t, accounts.Revalue("2023", prices))
+	account, _ := accounts.Account("fund")
+	assert.Equal(t, History{Date: "2023", Amount: money.FromInt(120), Holdings: []Holding{{"ABC", 10, 12}}}, account.History[1])
+	account, _ = accounts.Account("cash")
+	assert.Len(t, account.History, 1)
+
+	assert.NoError(t, accounts.UpdateHolding("fund", "2023", Holding{Security: "DEF", Units: 1, Price: 1}))
+	assert.NoError(t, prices.Add("ABC", "2023", 13))
+	assert.EqualError(t, accounts.Revalue("2023", prices), "No prices for DEF")
+	account, _ = accounts.Account("fund")
+	assert.Equal(t, History{Date: "2023", Amount: money.FromInt(121), Holdings: []Holding{{"ABC", 10, 12}, {"DEF", 1, 1}}}, account.History[1])
+}
+
+func TestRevalueAccount(t *testing.T) {
+	accounts := New()
+	assert.NoError(t, accounts.AddEmptyAccount("fund"))
+	assert.NoError(t, accounts.AddEmptyAccount("other"))
+	assert.NoError(t, accounts.UpdateHolding("fund", "2022", Holding{Security: "ABC", Units: 10, Price: 10}))
+	assert.NoError(t, accounts.UpdateHolding("other", "2022", Holding{Security: "ABC", Units: 1, Price: 10}))
+	prices := NewTable()
+	assert.NoError(t, prices.Add("ABC", "2023", 12))
+
+	assert.NoError(t, accounts.RevalueAccount("fund", "2023", prices))
+	account, _ := accounts.Account("fund")
+	assert.Len(t, account.History, 2)
+	account, _ = accounts.Account("other")
+	assert.Len(t, account.History, 1)
+	assert.Error(t, accounts.RevalueAccount("missing", "2023", prices))
+}
+
+func TestUpdateAmountWithHoldings(t *testing.T) {
+	accounts := New()
+	assert.NoError(t, accounts.AddEmptyAccount("fund"))
+	assert.NoError(t, accounts.UpdateHolding("fund", "2022", Holding{Security: "ABC", Units: 10, Price: 10}))
+
+	assert.EqualError(t, accounts.UpdateAmount("fund", "2022", money.FromInt(50)), "Amount at 2022 is the value of the holdings, update the holdings instead")
+	assert.NoError(t, accounts.UpdateAmount("fund", "2022", money.FromInt(100)))
+	assert.NoError(t, accounts.UpdateAmount("fund", "2023", money.FromInt(50)))
+}
+
+func TestHoldingsIncrease(t *testing.T) {
+	accounts := New()
+	assert.NoError(t, accounts.AddEmptyAccount("fund"))
+	assert.NoError(t, accounts.UpdateHolding("fund", "2022", Holding{Security: "ABC", Units: 10, Price: 10}))
+	assert.NoError(t, accounts.UpdateHolding("fund", "2023", Holding{Security: "ABC", Units: 15, Price: 11}))
+	assert.NoError(t, accounts.UpdateHolding("fund", "2023", Holding{Security: "DEF", Units: 1, Price: 5}))
+
+	holdings, err := accounts.Holdings("fund", ViewOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, []HoldingEntry{
+		{Year: "2022", Security: "ABC", Units: 10, Price: 10, Value: money.FromInt(100)},
+		{Year: "2023", Security: "ABC", Units: 15, Price: 11, Value: money.FromInt(165), Increase: money.FromInt(10)},
+		{Year: "2023", Security: "DEF", Units: 1, Price: 5, Value: money.FromInt(5)},
+	}, holdings)
+}
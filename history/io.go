@@ -1,86 +1,179 @@
 package history
 
 import (
+	"crypto/sha256"
 	"fmt"
 	"io"
 	"os"
-	"strings"
+	"path/filepath"
 	"sync"
-
-	"gopkg.in/yaml.v3"
 )
 
 func Load(filename string, initHistory bool) (*Accounts, error) {
-	reader, err := os.Open(filename)
+	return Open(YAML{}, filename, initHistory)
+}
+
+func Open(storage Storage, filename string, initHistory bool) (*Accounts, error) {
+	result, state, err := loadFile(storage, filename)
 	if os.IsNotExist(err) && initHistory {
-		return &Accounts{nil, &sync.Mutex{}}, nil
+		result, err = New(), nil
+		result.storage = storage
 	}
 	if err != nil {
 		return nil, err
 	}
-	defer reader.Close()
-	result, err := LoadFrom(reader)
+	result.file = state
+	var key *Key
+	if yaml, ok := storage.(YAML); ok {
+		key = yaml.Key
+	}
+	if result.journalFile, result.events, err = openJournal(filename, key); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func loadFile(storage Storage, filename string) (*Accounts, fileState, error) {
+	state, err := readFileState(filename)
+	if err != nil {
+		return nil, fileState{}, err
+	}
+	accounts, err := storage.Read(filename)
+	if err != nil {
+		return nil, fileState{}, fmt.Errorf("could not read %s: %w", filename, err)
+	}
+	result := newAccounts(accounts)
+	result.storage = storage
+	return result, state, nil
+}
+
+func readFileState(filename string) (fileState, error) {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return fileState{}, err
+	}
+	content, err := os.ReadFile(filename)
 	if err != nil {
-		return nil, fmt.Errorf("could not read %s: %w", filename, err)
+		return fileState{}, err
 	}
-	return result, err
+	return fileState{modTime: info.ModTime(), size: info.Size(), hash: sha256.Sum256(content)}, nil
 }
 
 func LoadFrom(reader io.Reader) (*Accounts, error) {
-	decoder := yaml.NewDecoder(reader)
-	var result []Account
-	for {
-		var account Account
-		err := decoder.Decode(&account)
-		if err == io.EOF {
-			return &Accounts{sortAccounts(result), &sync.Mutex{}}, nil
-		}
-		if err != nil {
-			return nil, err
+	accounts, err := readYAML(reader)
+	if err != nil {
+		return nil, err
+	}
+	return newAccounts(accounts), nil
+}
+
+func newAccounts(accounts []Account) *Accounts {
+	for _, account := range accounts {
+		for i := range account.History {
+			account.History[i] = account.History[i].withHoldings()
 		}
 		sortHistory(account.History)
-		result = append(result, account)
 	}
+	assignIDs(accounts)
+	result := &Accounts{accounts: sortAccounts(accounts, DefaultTagDefinitions), storage: YAML{}, lock: &sync.Mutex{}}
+	result.resetJournalLocked()
+	return result
 }
 
-func (a *Accounts) Save(filename string) error {
-	if _, err := os.Stat(filename); !os.IsNotExist(err) {
-		bak := strings.Join([]string{filename, "bak"}, ".")
-		copy(filename, bak)
-	}
+func readYAML(reader io.Reader) ([]Account, error) {
+	accounts, _, err := decodeYAML(reader)
+	return accounts, err
+}
 
-	writer, err := os.Create(filename)
+func (a *Accounts) Save(filename string, keep int) error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	status, err := a.checkFileLocked(filename)
 	if err != nil {
 		return err
 	}
-	encoder := yaml.NewEncoder(writer)
+	switch status {
+	case FileConflict:
+		return fmt.Errorf("%s was changed by someone else: %w", filename, ErrConflict)
+	case FileReloaded:
+		return nil
+	}
+	if err := backup(filename, keep); err != nil {
+		return err
+	}
+	return a.saveLocked(filename)
+}
 
+func (a *Accounts) SaveCopy(filename string) error {
+	return a.SaveAs(a.storage, filename)
+}
+
+func (a *Accounts) SaveAs(storage Storage, filename string) error {
 	a.lock.Lock()
 	defer a.lock.Unlock()
 
-	for _, account := range a.accounts {
-		err := encoder.Encode(account)
-		if err != nil {
-			return fmt.Errorf("could not write entry to %s: %w", filename, err)
+	return storage.Write(filename, a.accounts)
+}
+
+func (a *Accounts) saveLocked(filename string) error {
+	var previous []Account
+	if a.repository != nil {
+		var err error
+		if previous, err = a.repository.read(a.storage, "HEAD"); err != nil {
+			return err
 		}
 	}
-	return encoder.Close()
+	if err := a.storage.Write(filename, a.accounts); err != nil {
+		return err
+	}
+	state, err := readFileState(filename)
+	if err != nil {
+		return err
+	}
+	a.file = state
+	a.saved = a.revision
+	if err := a.flushJournalLocked(); err != nil {
+		return err
+	}
+	return a.commitLocked(previous)
 }
 
-func copy(from, to string) {
-	in, err := os.Open(from)
+func writeAtomic(filename string, write func(io.Writer) error) error {
+	dir := filepath.Dir(filename)
+	temp, err := os.CreateTemp(dir, filepath.Base(filename)+".tmp*")
 	if err != nil {
-		fmt.Printf("Could not write backup: %v\n", err)
-		return
+		return err
 	}
-	defer in.Close()
-	out, err := os.Create(to)
-	if err != nil {
-		fmt.Printf("Could not write backup: %v\n", err)
-		return
+	defer os.Remove(temp.Name())
+	if info, err := os.Stat(filename); err == nil {
+		if err := temp.Chmod(info.Mode().Perm()); err != nil {
+			temp.Close()
+			return err
+		}
+	}
+	if err := write(temp); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Sync(); err != nil {
+		temp.Close()
+		return err
 	}
-	_, err = io.Copy(out, in)
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(temp.Name(), filename); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
 	if err != nil {
-		fmt.Printf("Could not write backup: %v\n", err)
+		return err
 	}
+	defer d.Close()
+	return d.Sync()
 }
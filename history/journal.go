@@ -0,0 +1,458 @@
+package history
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jwiklund/ah/money"
+	"golang.org/x/exp/slices"
+)
+
+type Event struct {
+	Time    time.Time `json:"time"`
+	Action  string    `json:"action,omitempty"`
+	Account string    `json:"account"`
+	Name    string    `json:"name"`
+	Date    string    `json:"date,omitempty"`
+	Field   string    `json:"field"`
+	Old     string    `json:"old"`
+	New     string    `json:"new"`
+}
+
+type JournalFilter struct {
+	Account string
+	Date    string
+	Field   string
+}
+
+const maxUndo = 100
+
+type change struct {
+	before map[string]*Account
+	after  map[string]*Account
+}
+
+func (c change) ids() []string {
+	var ids []string
+	for id := range c.before {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func (a *Accounts) Journal(filter JournalFilter) []Event {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	var result []Event
+	for i := len(a.events) - 1; i >= 0; i-- {
+		event := a.events[i]
+		if filter.Account != "" && event.Account != filter.Account ||
+			filter.Date != "" && !strings.HasPrefix(event.Date, filter.Date) ||
+			filter.Field != "" && event.Field != filter.Field {
+			continue
+		}
+		result = append(result, event)
+	}
+	return result
+}
+
+func (a *Accounts) CanUndo() bool {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	return len(a.undo) > 0
+}
+
+func (a *Accounts) CanRedo() bool {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	return len(a.redo) > 0
+}
+
+func (a *Accounts) Undo() error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if len(a.undo) == 0 {
+		return errors.New("Nothing to undo")
+	}
+	latest := a.undo[len(a.undo)-1]
+	a.undo = a.undo[:len(a.undo)-1]
+	a.applyLocked(latest.before)
+	a.revision++
+	a.recordLocked("undo", latest.ids())
+	a.redo = append(a.redo, latest)
+	return nil
+}
+
+func (a *Accounts) Redo() error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if len(a.redo) == 0 {
+		return errors.New("Nothing to redo")
+	}
+	latest := a.redo[len(a.redo)-1]
+	a.redo = a.redo[:len(a.redo)-1]
+	a.applyLocked(latest.after)
+	a.revision++
+	a.recordLocked("redo", latest.ids())
+	a.undo = append(a.undo, latest)
+	return nil
+}
+
+func (a *Accounts) applyLocked(accounts map[string]*Account) {
+	for id, account := range accounts {
+		index := slices.IndexFunc(a.accounts, func(a Account) bool { return a.ID == id })
+		switch {
+		case account == nil && index != -1:
+			a.accounts = slices.Delete(a.accounts, index, index+1)
+		case account != nil && index != -1:
+			a.accounts[index] = cloneAccount(*account)
+		case account != nil:
+			a.accounts = append(a.accounts, cloneAccount(*account))
+		}
+	}
+	a.accounts = sortAccounts(a.accounts, a.tagDefinitionsLocked())
+}
+
+func (a *Accounts) editedLocked(action string, ids []string) {
+	edit := a.recordLocked(action, ids)
+	if len(edit.before) == 0 {
+		return
+	}
+	a.undo = append(a.undo, edit)
+	if len(a.undo) > maxUndo {
+		a.undo = slices.Delete(a.undo, 0, len(a.undo)-maxUndo)
+	}
+	a.redo = nil
+}
+
+func (a *Accounts) recordLocked(action string, ids []string) change {
+	if a.recorded == nil {
+		a.recorded = make(map[string]Account)
+	}
+	result := change{before: make(map[string]*Account), after: make(map[string]*Account)}
+	now := time.Now()
+	for _, id := range ids {
+		if _, done := result.before[id]; done {
+			continue
+		}
+		before, inBefore := a.recorded[id]
+		after, inAfter := findAccount(a.accounts, id)
+		if inAfter {
+			after = cloneAccount(after)
+			a.recorded[id] = after
+		} else {
+			delete(a.recorded, id)
+		}
+		events := accountEvents(before, inBefore, after, inAfter)
+		if len(events) == 0 {
+			continue
+		}
+		name := after.Name
+		if !inAfter {
+			name = before.Name
+		}
+		for _, event := range events {
+			event.Time, event.Action, event.Account, event.Name = now, action, id, name
+			a.events = append(a.events, event)
+			a.pending = append(a.pending, event)
+		}
+		result.before[id] = accountPointer(before, inBefore)
+		result.after[id] = accountPointer(after, inAfter)
+	}
+	return result
+}
+
+func (a *Accounts) allIDsLocked() []string {
+	ids := make([]string, 0, len(a.accounts))
+	for _, account := range a.accounts {
+		ids = append(ids, account.ID)
+	}
+	for id := range a.recorded {
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func (a *Accounts) resetJournalLocked() {
+	a.recorded = make(map[string]Account, len(a.accounts))
+	for _, account := range a.accounts {
+		a.recorded[account.ID] = cloneAccount(account)
+	}
+}
+
+func accountPointer(account Account, ok bool) *Account {
+	if !ok {
+		return nil
+	}
+	return &account
+}
+
+func cloneAccount(account Account) Account {
+	account.Tags = slices.Clone(account.Tags)
+	account.History = slices.Clone(account.History)
+	for i := range account.History {
+		account.History[i].Holdings = slices.Clone(account.History[i].Holdings)
+		account.History[i].Transfers = slices.Clone(account.History[i].Transfers)
+	}
+	return account
+}
+
+func accountEvents(before Account, inBefore bool, after Account, inAfter bool) []Event {
+	var events []Event
+	add := func(date, field, old, new string) {
+		if old != new {
+			events = append(events, Event{Date: date, Field: field, Old: old, New: new})
+		}
+	}
+	if !inBefore || !inAfter {
+		add("", "account", accountValue(before, inBefore), accountValue(after, inAfter))
+		return events
+	}
+	add("", "name", before.Name, after.Name)
+	add("", "kind", string(before.Kind), string(after.Kind))
+	add("", "currency", before.Currency, after.Currency)
+	add("", "closed", before.Closed, after.Closed)
+	add("", "tags", strings.Join(before.Tags, ", "), strings.Join(after.Tags, ", "))
+
+	var dates []string
+	for _, history := range [][]History{before.History, after.History} {
+		for _, entry := range history {
+			if !slices.Contains(dates, entry.Date) {
+				dates = append(dates, entry.Date)
+			}
+		}
+	}
+	slices.SortFunc(dates, CompareDates)
+	for _, date := range dates {
+		old := historyValues(before.History, date)
+		new := historyValues(after.History, date)
+		for i, field := range historyFields {
+			add(date, field, old[i], new[i])
+		}
+	}
+	return events
+}
+
+func accountValue(account Account, ok bool) string {
+	if !ok {
+		return ""
+	}
+	return account.Name
+}
+
+var historyFields = []string{"amount", "change", "withdrawal", "income", "fees", "holdings", "transfers"}
+
+func historyValues(history []History, date string) []string {
+	values := make([]string, len(historyFields))
+	index := historyIndex(history, date)
+	if index == -1 {
+		return values
+	}
+	entry := history[index]
+	values[0] = entry.Amount.String()
+	values[1] = entry.Change.String()
+	for i, amount := range []money.Amount{entry.Withdrawal, entry.Income, entry.Fees} {
+		if amount != 0 {
+			values[2+i] = amount.String()
+		}
+	}
+	var holdings []string
+	for _, holding := range entry.Holdings {
+		holdings = append(holdings, fmt.Sprintf("%s %g × %g", holding.Security, holding.Units, holding.Price))
+	}
+	values[5] = strings.Join(holdings, ", ")
+	var transfers []string
+	for _, transfer := range entry.Transfers {
+		transfers = append(transfers, fmt.Sprintf("%s %s %s", transfer.ID, transfer.Account, transfer.Amount))
+	}
+	values[6] = strings.Join(transfers, ", ")
+	return values
+}
+
+// encryptedJournal is followed by the salt that every line is encrypted with.
+const encryptedJournal = "AHENC1 "
+
+type journalFile struct {
+	name string
+	aead cipher.AEAD
+}
+
+func journalName(filename string) string {
+	return filename + ".journal"
+}
+
+func openJournal(filename string, key *Key) (*journalFile, []Event, error) {
+	file, events, err := readJournal(journalName(filename), key)
+	if err != nil || key == nil || file.aead != nil {
+		return file, events, err
+	}
+	file, err = writeJournal(journalName(filename), key, events)
+	return file, events, err
+}
+
+func ConvertJournal(filename string, from, to *Key) error {
+	name := journalName(filename)
+	if _, err := os.Stat(name); os.IsNotExist(err) {
+		return nil
+	}
+	_, events, err := readJournal(name, from)
+	if err != nil {
+		return err
+	}
+	_, err = writeJournal(name, to, events)
+	return err
+}
+
+func readJournal(name string, key *Key) (*journalFile, []Event, error) {
+	file := &journalFile{name: name}
+	content, err := os.ReadFile(name)
+	if os.IsNotExist(err) {
+		return file, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if end := bytes.LastIndexByte(content, '\n') + 1; end < len(content) {
+		// cut short by a crash while appending
+		if err := os.Truncate(name, int64(end)); err != nil {
+			return nil, nil, err
+		}
+		content = content[:end]
+	}
+	lines := strings.Split(string(content), "\n")
+	lines = lines[:len(lines)-1]
+	if len(lines) > 0 && strings.HasPrefix(lines[0], encryptedJournal) {
+		if key == nil {
+			return nil, nil, fmt.Errorf("%s is encrypted and no passphrase was given", name)
+		}
+		salt, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(lines[0], encryptedJournal))
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not read %s: %w", name, err)
+		}
+		if file.aead, err = key.aead(salt); err != nil {
+			return nil, nil, err
+		}
+		lines = lines[1:]
+	}
+	var events []Event
+	for i, line := range lines {
+		data, err := file.open(line)
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not read %s: %w", name, err)
+		}
+		var event Event
+		if err := json.Unmarshal(data, &event); err != nil {
+			return nil, nil, fmt.Errorf("could not read %s line %d: %w", name, i+1, err)
+		}
+		event.Time = event.Time.Local()
+		events = append(events, event)
+	}
+	return file, events, nil
+}
+
+func writeJournal(name string, key *Key, events []Event) (*journalFile, error) {
+	file := &journalFile{name: name}
+	var content bytes.Buffer
+	if key != nil {
+		salt := make([]byte, saltSize)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, err
+		}
+		var err error
+		if file.aead, err = key.aead(salt); err != nil {
+			return nil, err
+		}
+		content.WriteString(encryptedJournal + base64.StdEncoding.EncodeToString(salt) + "\n")
+	}
+	for _, event := range events {
+		line, err := file.seal(event)
+		if err != nil {
+			return nil, err
+		}
+		content.Write(line)
+	}
+	return file, writeAtomic(name, func(writer io.Writer) error {
+		_, err := writer.Write(content.Bytes())
+		return err
+	})
+}
+
+func (f *journalFile) append(events []Event) error {
+	var content bytes.Buffer
+	for _, event := range events {
+		line, err := f.seal(event)
+		if err != nil {
+			return err
+		}
+		content.Write(line)
+	}
+	file, err := os.OpenFile(f.name, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(content.Bytes()); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func (f *journalFile) seal(event Event) ([]byte, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+	if f.aead != nil {
+		nonce := make([]byte, f.aead.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return nil, err
+		}
+		data = []byte(base64.StdEncoding.EncodeToString(f.aead.Seal(nonce, nonce, data, nil)))
+	}
+	return append(data, '\n'), nil
+}
+
+func (f *journalFile) open(line string) ([]byte, error) {
+	if f.aead == nil {
+		return []byte(line), nil
+	}
+	data, err := base64.StdEncoding.DecodeString(line)
+	if err != nil || len(data) < f.aead.NonceSize() {
+		return nil, ErrPassphrase
+	}
+	size := f.aead.NonceSize()
+	plain, err := f.aead.Open(nil, data[:size], data[size:], nil)
+	if err != nil {
+		return nil, ErrPassphrase
+	}
+	return plain, nil
+}
+
+func (a *Accounts) flushJournalLocked() error {
+	if a.journalFile == nil || len(a.pending) == 0 {
+		return nil
+	}
+	if err := a.journalFile.append(a.pending); err != nil {
+		return fmt.Errorf("could not write %s: %w", a.journalFile.name, err)
+	}
+	a.pending = nil
+	return nil
+}
@@ -0,0 +1,133 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jwiklund/ah/money"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJournal(t *testing.T) {
+	accounts := transferAccounts(t)
+	assert.NoError(t, accounts.UpdateAmount("bank", "2023-06", money.FromInt(70)))
+	assert.NoError(t, accounts.UpdateAmount("bank", "2023-06", money.FromInt(75)))
+	assert.NoError(t, accounts.UpdateAmount("bank", "2023-06", money.FromInt(75)))
+	assert.NoError(t, accounts.Rename("broker", "Broker"))
+	assert.NoError(t, accounts.DeleteAccount("bank"))
+
+	events := accounts.Journal(JournalFilter{Account: "bank", Date: "2023", Field: "amount"})
+	assert.Len(t, events, 2)
+	assert.Equal(t, Event{Time: events[0].Time, Account: "bank", Name: "bank", Date: "2023-06", Field: "amount", Old: "70.00", New: "75.00"}, events[0])
+	assert.Equal(t, Event{Time: events[1].Time, Account: "bank", Name: "bank", Date: "2023-06", Field: "amount", Old: "", New: "70.00"}, events[1])
+
+	events = accounts.Journal(JournalFilter{Field: "account"})
+	assert.Equal(t, []string{"", "bank"}, []string{events[0].New, events[0].Old})
+	events = accounts.Journal(JournalFilter{Field: "name"})
+	assert.Equal(t, []string{"broker", "Broker"}, []string{events[0].Old, events[0].New})
+}
+
+func TestUndoRedo(t *testing.T) {
+	accounts := transferAccounts(t)
+	assert.False(t, accounts.CanRedo())
+	assert.NoError(t, accounts.UpdateAmount("bank", "2023", money.FromInt(75)))
+	assert.NoError(t, accounts.DeleteAccount("bank"))
+
+	assert.NoError(t, accounts.Undo())
+	account, err := accounts.Account("bank")
+	assert.NoError(t, err)
+	assert.Equal(t, money.FromInt(75), account.History[1].Amount)
+	assert.NoError(t, accounts.Undo())
+	account, _ = accounts.Account("bank")
+	assert.Equal(t, money.FromInt(70), account.History[1].Amount)
+	assert.Equal(t, "undo", accounts.Journal(JournalFilter{})[0].Action)
+
+	assert.NoError(t, accounts.Redo())
+	account, _ = accounts.Account("bank")
+	assert.Equal(t, money.FromInt(75), account.History[1].Amount)
+	assert.True(t, accounts.CanRedo())
+
+	assert.NoError(t, accounts.UpdateChange("bank", "2023", money.FromInt(5)))
+	assert.False(t, accounts.CanRedo())
+	assert.EqualError(t, accounts.Redo(), "Nothing to redo")
+	assert.True(t, accounts.Dirty())
+}
+
+func TestUndoLimit(t *testing.T) {
+	accounts := transferAccounts(t)
+	for i := 0; i < maxUndo+10; i++ {
+		assert.NoError(t, accounts.UpdateChange("bank", "2023", money.Amount(i+1)))
+	}
+	for i := 0; i < maxUndo; i++ {
+		assert.NoError(t, accounts.Undo())
+	}
+	assert.False(t, accounts.CanUndo())
+	account, _ := accounts.Account("bank")
+	assert.Equal(t, money.Amount(10), account.History[1].Change)
+}
+
+func TestJournalPartialLine(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "accounts.txt")
+	accounts, err := Open(YAML{}, filename, true)
+	assert.NoError(t, err)
+	assert.NoError(t, accounts.AddAccount("bank", "2022"))
+	assert.NoError(t, accounts.Save(filename, 0))
+	file, err := os.OpenFile(journalName(filename), os.O_WRONLY|os.O_APPEND, 0o600)
+	assert.NoError(t, err)
+	_, err = file.WriteString(`{"action":"upd`)
+	assert.NoError(t, err)
+	assert.NoError(t, file.Close())
+
+	accounts, err = Open(YAML{}, filename, false)
+	assert.NoError(t, err)
+	assert.Len(t, accounts.Journal(JournalFilter{}), 1)
+	assert.NoError(t, accounts.Rename("bank", "Bank"))
+	assert.NoError(t, accounts.Save(filename, 0))
+	accounts, err = Open(YAML{}, filename, false)
+	assert.NoError(t, err)
+	assert.Len(t, accounts.Journal(JournalFilter{}), 2)
+}
+
+func TestJournalFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "accounts.txt")
+	accounts, err := Open(YAML{}, filename, true)
+	assert.NoError(t, err)
+	assert.NoError(t, accounts.AddAccount("bank", "2022"))
+	assert.NoError(t, accounts.UpdateAmount("bank", "2022", money.FromInt(10)))
+	assert.NoError(t, accounts.Save(filename, 0))
+
+	reopened, err := Open(YAML{}, filename, false)
+	assert.NoError(t, err)
+	written, read := accounts.Journal(JournalFilter{}), reopened.Journal(JournalFilter{})
+	assert.Len(t, read, 2)
+	for i := range written {
+		assert.True(t, written[i].Time.Equal(read[i].Time))
+		written[i].Time, read[i].Time = time.Time{}, time.Time{}
+	}
+	assert.Equal(t, written, read)
+
+	key, _ := NewKey("secret")
+	assert.NoError(t, EncryptFile(filename, key))
+	assert.NoError(t, ConvertJournal(filename, nil, key))
+	content, _ := os.ReadFile(journalName(filename))
+	assert.True(t, strings.HasPrefix(string(content), encryptedJournal))
+	assert.NotContains(t, string(content), "bank")
+
+	encrypted, err := Open(YAML{Key: key}, filename, false)
+	assert.NoError(t, err)
+	assert.NoError(t, encrypted.Rename("bank", "Bank"))
+	assert.Len(t, encrypted.Journal(JournalFilter{}), 3)
+	reopened, err = Open(YAML{Key: key}, filename, false)
+	assert.NoError(t, err)
+	assert.Len(t, reopened.Journal(JournalFilter{}), 2)
+	assert.NoError(t, encrypted.Save(filename, 0))
+	reopened, err = Open(YAML{Key: key}, filename, false)
+	assert.NoError(t, err)
+	assert.Len(t, reopened.Journal(JournalFilter{}), 3)
+	wrong, _ := NewKey("wrong")
+	_, _, err = readJournal(journalName(filename), wrong)
+	assert.ErrorIs(t, err, ErrPassphrase)
+}
@@ -0,0 +1,164 @@
+package history
+
+import (
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+func mergeDocuments(header *yaml.Node, documents []*yaml.Node, accounts []Account) ([]*yaml.Node, error) {
+	if header == nil {
+		header = &yaml.Node{}
+		if err := header.Encode(schemaHeader{Schema: SchemaVersion}); err != nil {
+			return nil, err
+		}
+		header = &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{header}}
+	}
+	schema := mappingValue(header, "schema")
+	schema.Value, schema.Tag, schema.Style = strconv.Itoa(SchemaVersion), "!!int", 0
+
+	result := []*yaml.Node{header}
+	written := make(map[string]bool)
+	for _, document := range documents {
+		var account Account
+		var ok bool
+		if id := mappingValue(document, "id"); id != nil {
+			account, ok = findAccount(accounts, id.Value)
+		} else if name := mappingValue(document, "name"); name != nil {
+			account, ok = findUnwrittenByName(accounts, name.Value, written)
+			if ok && document.Content[0].Kind == yaml.MappingNode {
+				setDocumentID(document, account.ID)
+			}
+		}
+		if !ok || written[account.ID] {
+			continue
+		}
+		node, err := accountNode(account)
+		if err != nil {
+			return nil, err
+		}
+		mergeNode(document.Content[0], node)
+		result = append(result, document)
+		written[account.ID] = true
+	}
+	for _, account := range accounts {
+		if written[account.ID] {
+			continue
+		}
+		node, err := accountNode(account)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{node}})
+	}
+	return result, nil
+}
+
+func findUnwrittenByName(accounts []Account, name string, written map[string]bool) (Account, bool) {
+	for _, account := range accounts {
+		if !written[account.ID] && NameToSlug(account.Name) == NameToSlug(name) {
+			return account, true
+		}
+	}
+	return Account{}, false
+}
+
+func accountNode(account Account) (*yaml.Node, error) {
+	var node yaml.Node
+	if err := node.Encode(account); err != nil {
+		return nil, err
+	}
+	return &node, nil
+}
+
+func mergeNode(old, new *yaml.Node) {
+	if old.Kind != new.Kind {
+		head, line, foot := old.HeadComment, old.LineComment, old.FootComment
+		*old = *new
+		old.HeadComment, old.LineComment, old.FootComment = head, line, foot
+		return
+	}
+	switch old.Kind {
+	case yaml.ScalarNode:
+		if !sameScalar(old, new) {
+			old.Value, old.Tag, old.Style = new.Value, new.Tag, new.Style
+		}
+	case yaml.MappingNode:
+		var content []*yaml.Node
+		for i := 0; i+1 < len(old.Content); i += 2 {
+			if value := mappingValue(new, old.Content[i].Value); value != nil {
+				mergeNode(old.Content[i+1], value)
+				content = append(content, old.Content[i], old.Content[i+1])
+			}
+		}
+		for i := 0; i+1 < len(new.Content); i += 2 {
+			if mappingValue(old, new.Content[i].Value) == nil {
+				content = append(content, new.Content[i], new.Content[i+1])
+			}
+		}
+		old.Content = content
+	case yaml.SequenceNode:
+		key := sequenceKey(new)
+		used := make([]bool, len(old.Content))
+		var content []*yaml.Node
+		for i, item := range new.Content {
+			match := -1
+			for j, candidate := range old.Content {
+				if used[j] {
+					continue
+				}
+				if key == "" && i == j || key != "" && sameKey(candidate, item, key) {
+					match = j
+					break
+				}
+			}
+			if match == -1 {
+				content = append(content, item)
+				continue
+			}
+			used[match] = true
+			mergeNode(old.Content[match], item)
+			content = append(content, old.Content[match])
+		}
+		old.Content = content
+	}
+}
+
+func sequenceKey(sequence *yaml.Node) string {
+	for _, key := range []string{"date", "security", "id"} {
+		found := len(sequence.Content) > 0
+		for _, item := range sequence.Content {
+			found = found && mappingValue(item, key) != nil
+		}
+		if found {
+			return key
+		}
+	}
+	return ""
+}
+
+func sameKey(a, b *yaml.Node, key string) bool {
+	first, second := mappingValue(a, key), mappingValue(b, key)
+	return first != nil && second != nil && first.Value == second.Value
+}
+
+// sameScalar keeps 100.50 as written when the amount is unchanged.
+func sameScalar(a, b *yaml.Node) bool {
+	if a.Value == b.Value {
+		return true
+	}
+	if !isNumber(a) || !isNumber(b) {
+		return false
+	}
+	first, err := strconv.ParseFloat(a.Value, 64)
+	if err != nil {
+		return false
+	}
+	second, err := strconv.ParseFloat(b.Value, 64)
+	return err == nil && first == second
+}
+
+func isNumber(node *yaml.Node) bool {
+	tag := node.ShortTag()
+	return tag == "!!int" || tag == "!!float"
+}
@@ -0,0 +1,123 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jwiklund/ah/money"
+	"github.com/stretchr/testify/assert"
+)
+
+const commentedExample = `# Savings, updated yearly
+schema: 2
+---
+id: bank
+name: Bank # main account
+history:
+  # opened 2022
+  - date: "2022"
+    amount: 100.50
+    change: 100
+  - date: "2023"
+    amount: 120 # before interest
+    change: 10
+tags: [cash]
+---
+# Sold in 2023
+id: house
+name: House
+history:
+  - date: "2022"
+    amount: 1000
+    change: 0
+tags: []
+`
+
+const commentedExampleSaved = `# Savings, updated yearly
+schema: 2
+---
+id: bank
+name: Bank # main account
+history:
+  # opened 2022
+  - date: "2022"
+    amount: 100.50
+    change: 100
+  - date: "2023"
+    amount: 125 # before interest
+    change: 10
+    income: 5
+  - date: "2024"
+    amount: 125
+    change: 0
+tags: [cash, savings]
+---
+id: fund
+name: Fund
+history:
+  - date: "2024"
+    amount: 0
+    change: 0
+tags: []
+`
+
+func TestSavePreservesComments(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "accounts.txt")
+	assert.NoError(t, os.WriteFile(filename, []byte(commentedExample), 0644))
+	accounts, err := Load(filename, false)
+	assert.NoError(t, err)
+
+	assert.NoError(t, accounts.UpdateAmount("bank", "2023", money.FromInt(125)))
+	assert.NoError(t, accounts.UpdateIncome("bank", "2023", money.FromInt(5)))
+	assert.NoError(t, accounts.AddTag("bank", "savings"))
+	assert.NoError(t, accounts.DeleteAccount("house"))
+	assert.NoError(t, accounts.AddDate("2024"))
+	assert.NoError(t, accounts.AddAccount("Fund", "2024"))
+	assert.NoError(t, accounts.Save(filename, 0))
+
+	content, err := os.ReadFile(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, commentedExampleSaved, string(content))
+}
+
+func TestIndentation(t *testing.T) {
+	assert.Equal(t, 2, indentation([]byte(commentedExample)))
+	assert.Equal(t, 4, indentation([]byte("name: a\nhistory:\n    - date: \"2022\"\n      amount: 1\n")))
+	assert.Equal(t, defaultIndent, indentation([]byte("name: a\nhistory: []\n")))
+}
+
+const legacyCommentedExample = `# my savings
+name: b-savings
+history:
+  - date: "2022"
+    amount: 100 # bonus
+    change: 100
+---
+# the bank
+name: a-bank
+history: []
+`
+
+func TestSaveLegacyPreservesComments(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "accounts.txt")
+	assert.NoError(t, os.WriteFile(filename, []byte(legacyCommentedExample), 0644))
+	accounts, err := Load(filename, false)
+	assert.NoError(t, err)
+	assert.NoError(t, accounts.Save(filename, 0))
+
+	saved := "schema: 2\n---\n# my savings\nid: b-savings\nname: b-savings\nhistory:\n  - date: \"2022\"\n    amount: 100 # bonus\n    change: 100\ntags: []\n---\n# the bank\nid: a-bank\nname: a-bank\nhistory: []\ntags: []\n"
+	content, err := os.ReadFile(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, saved, string(content))
+
+	missingID := strings.Replace(saved, "id: a-bank\n", "", 1)
+	assert.NoError(t, os.WriteFile(filename, []byte(missingID), 0644))
+	accounts, err = Load(filename, false)
+	assert.NoError(t, err)
+	assert.NoError(t, accounts.Save(filename, 0))
+	content, err = os.ReadFile(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, saved, string(content))
+}
@@ -0,0 +1,172 @@
+package history
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+type Granularity string
+
+const (
+	AsRecorded Granularity = ""
+	Yearly     Granularity = "year"
+	Quarterly  Granularity = "quarter"
+	Monthly    Granularity = "month"
+)
+
+var Granularities = []Granularity{Yearly, Quarterly, Monthly}
+
+var (
+	yearPattern    = regexp.MustCompile(`^(\d{4})$`)
+	quarterPattern = regexp.MustCompile(`^(\d{4})-Q([1-4])$`)
+	monthPattern   = regexp.MustCompile(`^(\d{4})-(0[1-9]|1[0-2])$`)
+)
+
+type period struct {
+	year        int
+	month       int
+	granularity Granularity
+}
+
+func parsePeriod(date string) (period, error) {
+	if m := yearPattern.FindStringSubmatch(date); m != nil {
+		year, _ := strconv.Atoi(m[1])
+		return period{year, 12, Yearly}, nil
+	}
+	if m := quarterPattern.FindStringSubmatch(date); m != nil {
+		year, _ := strconv.Atoi(m[1])
+		quarter, _ := strconv.Atoi(m[2])
+		return period{year, quarter * 3, Quarterly}, nil
+	}
+	if m := monthPattern.FindStringSubmatch(date); m != nil {
+		year, _ := strconv.Atoi(m[1])
+		month, _ := strconv.Atoi(m[2])
+		return period{year, month, Monthly}, nil
+	}
+	return period{}, fmt.Errorf("invalid date %s, must match YYYY, YYYY-Qn or YYYY-MM", date)
+}
+
+func (p period) rank() int {
+	switch p.granularity {
+	case Monthly:
+		return 0
+	case Quarterly:
+		return 1
+	default:
+		return 2
+	}
+}
+
+func (p period) format(granularity Granularity) string {
+	if granularity == AsRecorded {
+		granularity = p.granularity
+	}
+	switch granularity {
+	case Monthly:
+		return fmt.Sprintf("%04d-%02d", p.year, p.month)
+	case Quarterly:
+		return fmt.Sprintf("%04d-Q%d", p.year, (p.month+2)/3)
+	default:
+		return fmt.Sprintf("%04d", p.year)
+	}
+}
+
+func (p period) after(other period) bool {
+	return p.year > other.year || p.year == other.year && p.month > other.month
+}
+
+func (g Granularity) coarser(other Granularity) bool {
+	return period{granularity: g}.rank() > period{granularity: other}.rank()
+}
+
+func ValidDate(date string) bool {
+	_, err := parsePeriod(date)
+	return err == nil
+}
+
+func ValidateDate(date string) error {
+	_, err := parsePeriod(date)
+	return err
+}
+
+func RollUp(date string, granularity Granularity) string {
+	p, err := parsePeriod(date)
+	if err != nil {
+		return date
+	}
+	return p.format(granularity)
+}
+
+// Dates are ordered by period end, finer periods first.
+func CompareDates(a, b string) int {
+	pa, errA := parsePeriod(a)
+	pb, errB := parsePeriod(b)
+	switch {
+	case errA != nil && errB != nil:
+		return strings.Compare(a, b)
+	case errA != nil:
+		return 1
+	case errB != nil:
+		return -1
+	}
+	if c := cmp.Compare(pa.year, pb.year); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(pa.month, pb.month); c != 0 {
+		return c
+	}
+	return cmp.Compare(pa.rank(), pb.rank())
+}
+
+// Yearly entries are shown as the last month or quarter next to sub-year ones.
+func finest(histories ...[]History) Granularity {
+	granularity := Yearly
+	for _, history := range histories {
+		for _, h := range history {
+			if p, err := parsePeriod(h.Date); err == nil && granularity.coarser(p.granularity) {
+				granularity = p.granularity
+			}
+		}
+	}
+	return granularity
+}
+
+func accountsGranularity(accounts []Account, granularity Granularity) Granularity {
+	if granularity != AsRecorded {
+		return granularity
+	}
+	var histories [][]History
+	for _, account := range accounts {
+		histories = append(histories, account.History)
+	}
+	return finest(histories...)
+}
+
+func rollUpHistory(history []History, granularity Granularity) []History {
+	if granularity == AsRecorded {
+		granularity = finest(history)
+	}
+	var result []History
+	for _, h := range history {
+		date := RollUp(h.Date, granularity)
+		if len(result) > 0 && result[len(result)-1].Date == date {
+			last := &result[len(result)-1]
+			last.Amount = h.Amount
+			last.Holdings = h.Holdings
+			last.Change = last.Change + h.Change
+			last.Withdrawal = last.Withdrawal + h.Withdrawal
+			last.Income = last.Income + h.Income
+			last.Fees = last.Fees + h.Fees
+			last.Transfers = append(slices.Clone(last.Transfers), h.Transfers...)
+			continue
+		}
+		h.Date = date
+		result = append(result, h)
+	}
+	return result
+}
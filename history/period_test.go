@@ -0,0 +1,118 @@
+package history
+
+import (
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidDate(t *testing.T) {
+	assert.True(t, ValidDate("2022"))
+	assert.True(t, ValidDate("2022-Q4"))
+	assert.True(t, ValidDate("2022-01"))
+	assert.False(t, ValidDate("2022-Q5"))
+	assert.False(t, ValidDate("2022-13"))
+	assert.False(t, ValidDate("22"))
+}
+
+func TestRollUp(t *testing.T) {
+	assert.Equal(t, "2022-Q2", RollUp("2022-05", Quarterly))
+	assert.Equal(t, "2022", RollUp("2022-05", Yearly))
+	assert.Equal(t, "2022", RollUp("2022-Q3", Yearly))
+	assert.Equal(t, "2022-09", RollUp("2022-Q3", Monthly))
+	assert.Equal(t, "2022-Q4", RollUp("2022", Quarterly))
+	assert.Equal(t, "2022-05", RollUp("2022-05", AsRecorded))
+}
+
+func TestCompareDates(t *testing.T) {
+	dates := []string{"2023", "2022", "2022-Q4", "2022-12", "2022-Q1", "2022-02"}
+	sort.Slice(dates, func(i, j int) bool {
+		return CompareDates(dates[i], dates[j]) < 0
+	})
+	assert.Equal(t, []string{"2022-02", "2022-Q1", "2022-12", "2022-Q4", "2022", "2023"}, dates)
+}
+
+func TestSummaryQuarterly(t *testing.T) {
+	accounts := &Accounts{
+		lock: &sync.Mutex{},
+		accounts: []Account{
+			{
+				Name: "name-1",
+				History: []History{
+					{Date: "2022-01", Amount: 1, Change: 1},
+					{Date: "2022-02", Amount: 3, Change: 1},
+					{Date: "2022-04", Amount: 4, Change: 0},
+				},
+			},
+		},
+	}
+	summary, _ := accounts.Summary(ViewOptions{Granularity: Quarterly})
+	assert.Equal(t, []SummaryEntry{
+		{Year: "2022-Q1", Assets: 3, Start: 0, End: 3, Change: 2, Contributions: 2, Increase: 1, Market: 1},
+		{Year: "2022-Q2", Assets: 4, Start: 3, End: 4, Change: 0, Increase: 1, Market: 1},
+	}, summary)
+
+	summary, _ = accounts.Summary(ViewOptions{Granularity: Yearly})
+	assert.Equal(t, []SummaryEntry{
+		{Year: "2022", Assets: 4, Start: 0, End: 4, Change: 2, Contributions: 2, Increase: 2, Market: 2},
+	}, summary)
+}
+
+func TestSummaryMixed(t *testing.T) {
+	accounts := &Accounts{
+		lock: &sync.Mutex{},
+		accounts: []Account{
+			{
+				Name: "bank",
+				History: []History{
+					{Date: "2022", Amount: 100, Change: 100},
+					{Date: "2023", Amount: 100},
+				},
+			},
+			{
+				Name: "fund",
+				History: []History{
+					{Date: "2023-01", Amount: 50, Change: 50},
+					{Date: "2023-12", Amount: 50},
+				},
+			},
+		},
+	}
+	summary, _ := accounts.Summary(ViewOptions{Granularity: Quarterly})
+	assert.Equal(t, []SummaryEntry{
+		{Year: "2022-Q4", Assets: 100, Start: 0, End: 100, Change: 100, Contributions: 100},
+		{Year: "2023-Q1", Assets: 150, Start: 100, End: 150, Change: 50, Contributions: 50},
+		{Year: "2023-Q4", Assets: 150, Start: 150, End: 150},
+	}, summary)
+
+	summary, _ = accounts.Summary(ViewOptions{})
+	assert.Equal(t, []SummaryEntry{
+		{Year: "2022-12", Assets: 100, Start: 0, End: 100, Change: 100, Contributions: 100},
+		{Year: "2023-01", Assets: 150, Start: 100, End: 150, Change: 50, Contributions: 50},
+		{Year: "2023-12", Assets: 150, Start: 150, End: 150},
+	}, summary)
+
+	summary, _ = accounts.Summary(ViewOptions{Granularity: Yearly})
+	assert.Equal(t, []SummaryEntry{
+		{Year: "2022", Assets: 100, Start: 0, End: 100, Change: 100, Contributions: 100},
+		{Year: "2023", Assets: 150, Start: 100, End: 150, Change: 50, Contributions: 50},
+	}, summary)
+}
+
+func TestAddDate(t *testing.T) {
+	accounts := &Accounts{
+		lock: &sync.Mutex{},
+		accounts: []Account{
+			makeAccount("a", "2022-11", 1, false),
+		},
+	}
+	assert.Error(t, accounts.AddDate("2022-13"))
+	assert.NoError(t, accounts.AddDate("2022-12"))
+	assert.Equal(t, []History{
+		{Date: "2022-11", Amount: 1},
+		{Date: "2022-12", Amount: 1},
+	}, accounts.accounts[0].History)
+	assert.Equal(t, "2022-12", accounts.CurrentDate())
+}
@@ -0,0 +1,92 @@
+package history
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"sort"
+
+	"github.com/jwiklund/ah/money"
+)
+
+type ProjectionOptions struct {
+	Return       float64
+	Volatility   float64
+	Contribution money.Amount
+	Years        int
+	Simulations  int
+}
+
+type ProjectionEntry struct {
+	Year     string
+	Expected money.Amount
+	P10      money.Amount
+	P50      money.Amount
+	P90      money.Amount
+}
+
+type projectionStep struct {
+	year     int
+	fraction float64
+}
+
+// Project simulates log-normal yearly growth, the first step only covers the rest of the year.
+func Project(start money.Amount, year string, opts ProjectionOptions, rng *rand.Rand) ([]ProjectionEntry, error) {
+	p, err := parsePeriod(year)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Years < 1 || opts.Years > 100 {
+		return nil, errors.New("Horizon must be between 1 and 100 years")
+	}
+	if opts.Return <= -1 {
+		return nil, errors.New("Expected return must be above -100%")
+	}
+	if opts.Volatility < 0 {
+		return nil, errors.New("Volatility can not be negative")
+	}
+	if opts.Simulations < 1 {
+		return nil, errors.New("At least one simulation is needed")
+	}
+	var steps []projectionStep
+	if p.month < 12 {
+		steps = append(steps, projectionStep{p.year, float64(12-p.month) / 12})
+	}
+	for i := 1; i <= opts.Years; i++ {
+		steps = append(steps, projectionStep{p.year + i, 1})
+	}
+	drift := math.Log(1+opts.Return) - opts.Volatility*opts.Volatility/2
+	contribution := float64(opts.Contribution)
+	simulations := make([][]float64, len(steps))
+	for i := range simulations {
+		simulations[i] = make([]float64, opts.Simulations)
+	}
+	for s := 0; s < opts.Simulations; s++ {
+		value := float64(start)
+		for i, step := range steps {
+			growth := drift*step.fraction + opts.Volatility*math.Sqrt(step.fraction)*rng.NormFloat64()
+			value = value*math.Exp(growth) + contribution*step.fraction
+			simulations[i][s] = value
+		}
+	}
+	var result []ProjectionEntry
+	expected := float64(start)
+	for i, values := range simulations {
+		step := steps[i]
+		expected = expected*math.Pow(1+opts.Return, step.fraction) + contribution*step.fraction
+		sort.Float64s(values)
+		result = append(result, ProjectionEntry{
+			Year:     fmt.Sprintf("%04d", step.year),
+			Expected: money.Amount(math.Round(expected)),
+			P10:      percentile(values, 0.1),
+			P50:      percentile(values, 0.5),
+			P90:      percentile(values, 0.9),
+		})
+	}
+	return result, nil
+}
+
+func percentile(sorted []float64, p float64) money.Amount {
+	return money.Amount(math.Round(sorted[int(math.Round(p*float64(len(sorted)-1)))]))
+}
@@ -0,0 +1,66 @@
+package history
+
+import (
+	"math/rand/v2"
+	"testing"
+
+	"github.com/jwiklund/ah/money"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProjectExpected(t *testing.T) {
+	projection, err := Project(money.FromInt(100), "2023", ProjectionOptions{
+		Return:       0.1,
+		Contribution: money.FromInt(10),
+		Years:        2,
+		Simulations:  10,
+	}, rand.New(rand.NewPCG(1, 2)))
+	assert.NoError(t, err)
+	assert.Equal(t, []ProjectionEntry{
+		{Year: "2024", Expected: money.FromInt(120), P10: money.FromInt(120), P50: money.FromInt(120), P90: money.FromInt(120)},
+		{Year: "2025", Expected: money.FromInt(142), P10: money.FromInt(142), P50: money.FromInt(142), P90: money.FromInt(142)},
+	}, projection)
+}
+
+func TestProjectSeeded(t *testing.T) {
+	opts := ProjectionOptions{Return: 0.05, Volatility: 0.2, Years: 10, Simulations: 1000}
+	first, err := Project(money.FromInt(1000), "2023-Q2", opts, rand.New(rand.NewPCG(1, 2)))
+	assert.NoError(t, err)
+	second, err := Project(money.FromInt(1000), "2023-Q2", opts, rand.New(rand.NewPCG(1, 2)))
+	assert.NoError(t, err)
+	assert.Equal(t, first, second)
+
+	assert.Equal(t, 11, len(first))
+	assert.Equal(t, "2023", first[0].Year)
+	last := first[len(first)-1]
+	assert.Equal(t, "2033", last.Year)
+	assert.Less(t, last.P10, last.P50)
+	assert.Less(t, last.P50, last.Expected)
+	assert.Less(t, last.Expected, last.P90)
+}
+
+func TestProjectQuarter(t *testing.T) {
+	projection, err := Project(money.FromInt(100), "2023-Q2", ProjectionOptions{
+		Return:       0.21,
+		Contribution: money.FromInt(10),
+		Years:        1,
+		Simulations:  10,
+	}, rand.New(rand.NewPCG(1, 2)))
+	assert.NoError(t, err)
+	assert.Equal(t, []ProjectionEntry{
+		{Year: "2023", Expected: money.FromInt(115), P10: money.FromInt(115), P50: money.FromInt(115), P90: money.FromInt(115)},
+		{Year: "2024", Expected: money.Amount(14915), P10: money.Amount(14915), P50: money.Amount(14915), P90: money.Amount(14915)},
+	}, projection)
+}
+
+func TestProjectOptions(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	_, err := Project(0, "2023", ProjectionOptions{Years: 0, Simulations: 1}, rng)
+	assert.Error(t, err)
+	_, err = Project(0, "2023", ProjectionOptions{Years: 1, Volatility: -1, Simulations: 1}, rng)
+	assert.Error(t, err)
+	_, err = Project(0, "2023", ProjectionOptions{Years: 1}, rng)
+	assert.Error(t, err)
+	_, err = Project(0, "now", ProjectionOptions{Years: 1, Simulations: 1}, rng)
+	assert.Error(t, err)
+}
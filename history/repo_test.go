@@ -5,6 +5,7 @@ import (
 	"sync"
 	"testing"
 
+	"github.com/jwiklund/ah/money"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -25,31 +26,40 @@ history:
   change: 0
 `
 
+// testAccounts loads the accounts of a yaml example.
+func testAccounts(t *testing.T, example string) *Accounts {
+	accounts, err := LoadFrom(bytes.NewBufferString(example))
+	assert.NoError(t, err)
+	return accounts
+}
+
 func TestLoadFrom(t *testing.T) {
 	accounts, err := LoadFrom(bytes.NewBuffer([]byte(loadFromExample)))
 	assert.NoError(t, err)
 	assert.Equal(t, []Account{
 		{
+			ID:   "name-1",
 			Name: "name-1",
 			History: []History{
 				{
 					Date:   "2022",
-					Amount: 1,
-					Change: 1,
+					Amount: money.FromInt(1),
+					Change: money.FromInt(1),
 				},
 			},
 		},
 		{
+			ID:   "name-2",
 			Name: "name-2",
 			History: []History{
 				{
 					Date:   "2022",
-					Amount: 1,
-					Change: 1,
+					Amount: money.FromInt(1),
+					Change: money.FromInt(1),
 				},
 				{
 					Date:   "2023",
-					Amount: 2,
+					Amount: money.FromInt(2),
 					Change: 0,
 				},
 			},
@@ -93,21 +103,26 @@ func TestSummary(t *testing.T) {
 			},
 		},
 	}
-	summary, _ := accounts.Summary("")
+	summary, _ := accounts.Summary(ViewOptions{})
 	assert.Equal(t, []SummaryEntry{
 		{
-			Year:     "2022",
-			Start:    0,
-			End:      2,
-			Change:   2,
-			Increase: 0,
+			Year:          "2022",
+			Assets:        2,
+			Start:         0,
+			End:           2,
+			Change:        2,
+			Contributions: 2,
+			Increase:      0,
+			Market:        0,
 		},
 		{
 			Year:     "2023",
+			Assets:   3,
 			Start:    2,
 			End:      3,
 			Change:   0,
 			Increase: 1,
+			Market:   1,
 		},
 	}, summary)
 }
@@ -177,13 +192,14 @@ func TestCurrent(t *testing.T) {
 	}
 	accounts := &Accounts{
 		lock:     &sync.Mutex{},
-		accounts: sortAccounts(unsortedAccounts),
+		accounts: sortAccounts(unsortedAccounts, DefaultTagDefinitions),
 	}
-	summary := accounts.Current()
+	summary := accounts.Current(ViewOptions{})
 	assert.Equal(t, []CurrentEntry{
 		{
 			Name:     "name 3",
 			Slug:     "name-3",
+			Kind:     Asset,
 			Start:    2,
 			End:      2,
 			Change:   0,
@@ -192,6 +208,7 @@ func TestCurrent(t *testing.T) {
 		{
 			Name:     "name-0-2",
 			Slug:     "name-0-2",
+			Kind:     Asset,
 			Start:    1,
 			End:      0,
 			Change:   -1,
@@ -200,6 +217,7 @@ func TestCurrent(t *testing.T) {
 		{
 			Name:     "name-1",
 			Slug:     "name-1",
+			Kind:     Asset,
 			Start:    1,
 			End:      1,
 			Change:   0,
@@ -208,6 +226,7 @@ func TestCurrent(t *testing.T) {
 		{
 			Name:     "name-2",
 			Slug:     "name-2",
+			Kind:     Asset,
 			Start:    0,
 			End:      2,
 			Change:   0,
@@ -216,6 +235,7 @@ func TestCurrent(t *testing.T) {
 		{
 			Name:     "name-0",
 			Slug:     "name-0",
+			Kind:     Asset,
 			Start:    0,
 			End:      0,
 			Change:   0,
@@ -223,3 +243,80 @@ func TestCurrent(t *testing.T) {
 		},
 	}, summary)
 }
+
+func TestLoadFromDecimal(t *testing.T) {
+	accounts, err := LoadFrom(bytes.NewBufferString("name: name-1\nhistory:\n- date: 2022\n  amount: 1000.5\n  change: -0.25\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []History{
+		{
+			Date:   "2022",
+			Amount: 100050,
+			Change: -25,
+		},
+	}, accounts.accounts[0].History)
+}
+
+func TestSummaryLiability(t *testing.T) {
+	accounts := &Accounts{
+		lock: &sync.Mutex{},
+		accounts: []Account{
+			{
+				ID:   "bank",
+				Name: "bank",
+				History: []History{
+					{Date: "2022", Amount: 50, Change: 50},
+					{Date: "2023", Amount: 40, Change: -10},
+				},
+			},
+			{
+				ID:   "loan",
+				Name: "loan",
+				Kind: Liability,
+				History: []History{
+					{Date: "2022", Amount: 100, Change: 0},
+					{Date: "2023", Amount: 92, Change: 10},
+				},
+			},
+		},
+	}
+	summary, _ := accounts.Summary(ViewOptions{})
+	assert.Equal(t, []SummaryEntry{
+		{Year: "2022", Assets: 50, Liabilities: 100, Start: 0, End: -50, Change: 50, Contributions: 50, Increase: -100, Market: -100},
+		{Year: "2023", Assets: 40, Liabilities: 92, Start: -50, End: -52, Change: 0, Increase: -2, Market: -2},
+	}, summary)
+
+	_, history, err := accounts.AccountHistory("loan", ViewOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, SummaryEntry{Year: "2023", Start: 100, End: 92, Change: 10, Contributions: 10, Increase: -2, Market: -2}, history[1])
+}
+
+func TestSummaryFlows(t *testing.T) {
+	accounts, err := LoadFrom(bytes.NewBufferString(`
+name: broker
+history:
+  - date: "2022"
+    amount: 100
+    change: 100
+  - date: "2023"
+    amount: 125
+    change: 30
+    withdrawal: 10
+    income: 4
+    fees: 1
+`))
+	assert.NoError(t, err)
+	summary, _ := accounts.Summary(ViewOptions{})
+	assert.Equal(t, SummaryEntry{
+		Year:          "2023",
+		Assets:        money.FromInt(125),
+		Start:         money.FromInt(100),
+		End:           money.FromInt(125),
+		Change:        money.FromInt(20),
+		Contributions: money.FromInt(30),
+		Withdrawals:   money.FromInt(10),
+		Income:        money.FromInt(4),
+		Fees:          money.FromInt(1),
+		Increase:      money.FromInt(5),
+		Market:        money.FromInt(2),
+	}, summary[1])
+}
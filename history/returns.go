@@ -0,0 +1,160 @@
+package history
+
+import (
+	"fmt"
+	"math"
+
+	"golang.org/x/exp/slices"
+)
+
+type FlowTiming string
+
+const (
+	FlowStart  FlowTiming = "start"
+	FlowMiddle FlowTiming = "middle"
+	FlowEnd    FlowTiming = "end"
+)
+
+var FlowTimings = []FlowTiming{FlowStart, FlowMiddle, FlowEnd}
+
+func (a *Accounts) SetFlowTiming(timing FlowTiming) error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if !slices.Contains(FlowTimings, timing) {
+		return fmt.Errorf("Unknown flow timing: %s, must be start, middle or end", timing)
+	}
+	a.flowTiming = timing
+	return nil
+}
+
+func (t FlowTiming) weight() float64 {
+	switch t {
+	case FlowStart:
+		return 1
+	case FlowEnd:
+		return 0
+	default:
+		return 0.5
+	}
+}
+
+type Return struct {
+	Year   string
+	Return float64
+	TWR    float64
+	XIRR   *float64
+}
+
+func (a *Accounts) AccountReturns(id string, opts ViewOptions) ([]Return, error) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	index, err := a.indexLocked(id)
+	if err != nil {
+		return nil, err
+	}
+	account := a.accounts[index]
+	history, err := a.accountHistoryLocked(account, opts)
+	if err != nil {
+		return nil, err
+	}
+	return returns(history, a.flowTiming, account.Kind), nil
+}
+
+func (a *Accounts) SummaryReturns(opts ViewOptions) []Return {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	summary, _ := a.summaryLocked(opts)
+	return returns(summary, a.flowTiming, Asset)
+}
+
+type cashFlow struct {
+	time   float64
+	amount float64
+}
+
+// returns uses modified Dietz for the period return.
+func returns(entries []SummaryEntry, timing FlowTiming, kind Kind) []Return {
+	account := Account{Kind: kind}
+	weight := timing.weight()
+	growth := 1.0
+	var flows []cashFlow
+	previousEnd := math.NaN()
+	var result []Return
+	for _, entry := range entries {
+		r := Return{Year: entry.Year}
+		start := float64(account.value(entry.Start))
+		end := float64(account.value(entry.End))
+		flow := float64(entry.Change + entry.Transfers + entry.Oneoff)
+		if base := start + weight*flow; base != 0 {
+			r.Return = (end - start - flow) / base
+		}
+		growth = growth * (1 + r.Return)
+		r.TWR = growth - 1
+
+		if periodStart, periodEnd, ok := periodTimes(entry.Year, previousEnd); ok {
+			if len(flows) == 0 && start != 0 {
+				flows = append(flows, cashFlow{time: periodStart, amount: -start})
+			}
+			if flow != 0 {
+				flows = append(flows, cashFlow{time: periodEnd - weight*(periodEnd-periodStart), amount: -flow})
+			}
+			if rate, ok := xirr(append(slices.Clone(flows), cashFlow{time: periodEnd, amount: end})); ok {
+				r.XIRR = &rate
+			}
+			previousEnd = periodEnd
+		}
+		result = append(result, r)
+	}
+	return result
+}
+
+func periodTimes(date string, previousEnd float64) (float64, float64, bool) {
+	p, err := parsePeriod(date)
+	if err != nil {
+		return 0, 0, false
+	}
+	end := float64(p.year) + float64(p.month)/12
+	if !math.IsNaN(previousEnd) && previousEnd < end {
+		return previousEnd, end, true
+	}
+	months := map[Granularity]float64{Yearly: 12, Quarterly: 3, Monthly: 1}[p.granularity]
+	return end - months/12, end, true
+}
+
+// xirr solves for a zero net present value by bisection.
+func xirr(flows []cashFlow) (float64, bool) {
+	var in, out bool
+	for _, f := range flows {
+		in = in || f.amount < 0
+		out = out || f.amount > 0
+	}
+	if !in || !out || flows[len(flows)-1].time <= flows[0].time {
+		return 0, false
+	}
+	npv := func(rate float64) float64 {
+		var sum float64
+		for _, f := range flows {
+			sum += f.amount / math.Pow(1+rate, f.time-flows[0].time)
+		}
+		return sum
+	}
+	low, high := -0.99, 1.0
+	for npv(low)*npv(high) > 0 {
+		if high > 1e6 {
+			return 0, false
+		}
+		high = high * 2
+	}
+	for i := 0; i < 100; i++ {
+		middle := (low + high) / 2
+		if npv(low)*npv(middle) <= 0 {
+			high = middle
+		} else {
+			low = middle
+		}
+	}
+	return (low + high) / 2, true
+}
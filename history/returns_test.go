@@ -0,0 +1,65 @@
+package history
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const returnExample = `
+name: isk
+history:
+- date: "2022"
+  amount: 1.00
+  change: 1.00
+- date: "2023"
+  amount: 1.65
+  change: 0.50
+tags: [savings]
+`
+
+func TestAccountReturns(t *testing.T) {
+	accounts := testAccounts(t, returnExample)
+	assert.NoError(t, accounts.SetFlowTiming(FlowStart))
+
+	history, err := accounts.AccountReturns("isk", ViewOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, history, 2)
+	assert.Equal(t, "2023", history[1].Year)
+	assert.InDelta(t, 0, history[0].Return, 1e-9)
+	assert.InDelta(t, 0.1, history[1].Return, 1e-9)
+	assert.InDelta(t, 0.1, history[1].TWR, 1e-9)
+	assert.InDelta(t, 0, *history[0].XIRR, 1e-9)
+	// -100 at the start of 2022, -50 at the start of 2023 and +165 at the end
+	assert.InDelta(t, (-50+math.Sqrt(50*50+4*100*165))/200-1, *history[1].XIRR, 1e-6)
+}
+
+func TestSummaryReturns(t *testing.T) {
+	accounts := testAccounts(t, returnExample)
+	assert.NoError(t, accounts.SetFlowTiming(FlowEnd))
+
+	summary := accounts.SummaryReturns(ViewOptions{Tag: "savings"})
+	assert.Len(t, summary, 2)
+	assert.Equal(t, 0.0, summary[0].Return)
+	assert.InDelta(t, 0.15, summary[1].Return, 1e-9)
+	assert.InDelta(t, 0.15, summary[1].TWR, 1e-9)
+	// -100 at the end of 2022, -50 and +165 at the end of 2023
+	assert.InDelta(t, 0.15, *summary[1].XIRR, 1e-6)
+	// -100 and +100 at the end of 2022 have no rate
+	assert.Nil(t, summary[0].XIRR)
+
+	assert.Empty(t, accounts.SummaryReturns(ViewOptions{Tag: "pension"}))
+	assert.Error(t, accounts.SetFlowTiming("sometime"))
+	_, err := accounts.AccountReturns("missing", ViewOptions{})
+	assert.Error(t, err)
+}
+
+func TestXIRR(t *testing.T) {
+	rate, ok := xirr([]cashFlow{{time: 2022, amount: -100}, {time: 2022.5, amount: -100}, {time: 2024, amount: 230}})
+	assert.True(t, ok)
+	npv := -100 - 100/math.Pow(1+rate, 0.5) + 230/math.Pow(1+rate, 2)
+	assert.InDelta(t, 0, npv, 1e-6)
+	_, ok = xirr([]cashFlow{{time: 2022, amount: -100}})
+	assert.False(t, ok)
+}
@@ -0,0 +1,20 @@
+package history
+
+func (a *Accounts) Revision() uint64 {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	return a.revision
+}
+
+func (a *Accounts) Dirty() bool {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	return a.revision != a.saved
+}
+
+func (a *Accounts) changedLocked(ids ...string) {
+	a.revision++
+	a.editedLocked("", ids)
+}
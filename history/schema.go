@@ -0,0 +1,166 @@
+package history
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+const SchemaVersion = 2
+
+type schemaHeader struct {
+	Schema int `yaml:"schema"`
+}
+
+type Migration struct {
+	Version     int
+	Description string
+	Apply       func(documents []*yaml.Node) ([]string, error)
+}
+
+var Migrations = []Migration{
+	{Version: 2, Description: "Assign stable account ids", Apply: migrateIDs},
+}
+
+type MigrationReport struct {
+	From    int
+	To      int
+	Changes map[int][]string
+}
+
+func decodeYAML(reader io.Reader) ([]Account, MigrationReport, error) {
+	_, documents, report, err := decodeDocuments(reader)
+	if err != nil {
+		return nil, report, err
+	}
+	var result []Account
+	for _, document := range documents {
+		var account Account
+		if err := document.Decode(&account); err != nil {
+			return nil, report, err
+		}
+		result = append(result, account)
+	}
+	return result, report, nil
+}
+
+func decodeDocuments(reader io.Reader) (*yaml.Node, []*yaml.Node, MigrationReport, error) {
+	report := MigrationReport{From: 1, To: SchemaVersion}
+	decoder := yaml.NewDecoder(reader)
+	var header *yaml.Node
+	var documents []*yaml.Node
+	for {
+		var document yaml.Node
+		err := decoder.Decode(&document)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, report, err
+		}
+		if len(documents) == 0 && header == nil && isSchemaHeader(&document) {
+			var schema schemaHeader
+			if err := document.Decode(&schema); err != nil {
+				return nil, nil, report, err
+			}
+			header = &document
+			report.From = schema.Schema
+			continue
+		}
+		documents = append(documents, &document)
+	}
+	if report.From > SchemaVersion {
+		return nil, nil, report, fmt.Errorf("schema version %d is newer than the supported version %d", report.From, SchemaVersion)
+	}
+	for _, migration := range Migrations {
+		if migration.Version <= report.From {
+			continue
+		}
+		changes, err := migration.Apply(documents)
+		if err != nil {
+			return nil, nil, report, fmt.Errorf("could not migrate to schema version %d: %w", migration.Version, err)
+		}
+		if len(changes) > 0 {
+			if report.Changes == nil {
+				report.Changes = make(map[int][]string)
+			}
+			report.Changes[migration.Version] = changes
+		}
+	}
+	return header, documents, report, nil
+}
+
+func isSchemaHeader(document *yaml.Node) bool {
+	return mappingValue(document, "schema") != nil
+}
+
+func mappingValue(document *yaml.Node, key string) *yaml.Node {
+	node := document
+	if node.Kind == yaml.DocumentNode && len(node.Content) == 1 {
+		node = node.Content[0]
+	}
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// migrateIDs writes the ids assignIDs gives on load into the documents.
+func migrateIDs(documents []*yaml.Node) ([]string, error) {
+	accounts := make([]Account, len(documents))
+	for i, document := range documents {
+		if len(document.Content) != 1 || document.Content[0].Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("line %d: not an account", document.Line)
+		}
+		if err := document.Decode(&accounts[i]); err != nil {
+			return nil, err
+		}
+	}
+	previous := make([]string, len(accounts))
+	for i := range accounts {
+		previous[i] = accounts[i].ID
+	}
+	assignIDs(accounts)
+
+	var changes []string
+	for i, document := range documents {
+		if accounts[i].ID == previous[i] {
+			continue
+		}
+		setDocumentID(document, accounts[i].ID)
+		changes = append(changes, fmt.Sprintf("%s: assign id %s", accounts[i].Name, accounts[i].ID))
+	}
+	return changes, nil
+}
+
+func setDocumentID(document *yaml.Node, value string) {
+	if id := mappingValue(document, "id"); id != nil {
+		id.Value = value
+		return
+	}
+	mapping := document.Content[0]
+	key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "id"}
+	if len(mapping.Content) > 0 {
+		key.HeadComment, mapping.Content[0].HeadComment = mapping.Content[0].HeadComment, ""
+	}
+	mapping.Content = append([]*yaml.Node{
+		key,
+		{Kind: yaml.ScalarNode, Tag: "!!str", Value: value},
+	}, mapping.Content...)
+}
+
+func (y YAML) Migrations(filename string) (MigrationReport, error) {
+	content, err := y.content(filename)
+	if err != nil {
+		return MigrationReport{}, err
+	}
+	_, report, err := decodeYAML(bytes.NewReader(content))
+	return report, err
+}
@@ -0,0 +1,46 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const legacyExample = `name: Bank
+history: []
+---
+id: bank
+name: Other bank
+history: []
+`
+
+func TestMigrateLegacy(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "accounts.txt")
+	assert.NoError(t, os.WriteFile(filename, []byte(legacyExample), 0644))
+
+	report, err := YAML{}.Migrations(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, MigrationReport{From: 1, To: 2, Changes: map[int][]string{2: {"Bank: assign id bank-2"}}}, report)
+	content, _ := os.ReadFile(filename)
+	assert.Equal(t, legacyExample, string(content))
+
+	accounts, err := Load(filename, false)
+	assert.NoError(t, err)
+	assert.NoError(t, accounts.Save(filename, 0))
+	content, _ = os.ReadFile(filename)
+	assert.Equal(t, "schema: 2\n---\nid: bank-2\nname: Bank\nhistory: []\ntags: []\n---\nid: bank\nname: Other bank\nhistory: []\ntags: []\n", string(content))
+
+	report, err = YAML{}.Migrations(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, MigrationReport{From: 2, To: 2}, report)
+}
+
+func TestNewerSchema(t *testing.T) {
+	_, err := LoadFrom(strings.NewReader("schema: 3\n---\nid: bank\nname: bank\n"))
+	assert.EqualError(t, err, "schema version 3 is newer than the supported version 2")
+	_, err = LoadFrom(strings.NewReader("- name: bank\n"))
+	assert.EqualError(t, err, "could not migrate to schema version 2: line 1: not an account")
+}
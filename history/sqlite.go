@@ -0,0 +1,246 @@
+package history
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+
+	"github.com/jwiklund/ah/money"
+	_ "modernc.org/sqlite"
+)
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS accounts (
+	id       TEXT PRIMARY KEY,
+	position INTEGER NOT NULL,
+	name     TEXT NOT NULL,
+	kind     TEXT NOT NULL,
+	currency TEXT NOT NULL,
+	closed   TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS history (
+	account_id TEXT NOT NULL REFERENCES accounts (id) ON DELETE CASCADE,
+	date       TEXT NOT NULL,
+	amount     TEXT NOT NULL,
+	change     TEXT NOT NULL,
+	withdrawal TEXT NOT NULL,
+	income     TEXT NOT NULL,
+	fees       TEXT NOT NULL,
+	PRIMARY KEY (account_id, date)
+);
+CREATE TABLE IF NOT EXISTS holdings (
+	account_id TEXT NOT NULL,
+	date       TEXT NOT NULL,
+	position   INTEGER NOT NULL,
+	security   TEXT NOT NULL,
+	units      REAL NOT NULL,
+	price      REAL NOT NULL,
+	FOREIGN KEY (account_id, date) REFERENCES history (account_id, date) ON DELETE CASCADE
+);
+CREATE TABLE IF NOT EXISTS transfers (
+	account_id TEXT NOT NULL,
+	date       TEXT NOT NULL,
+	position   INTEGER NOT NULL,
+	id         TEXT NOT NULL,
+	other      TEXT NOT NULL,
+	amount     TEXT NOT NULL,
+	FOREIGN KEY (account_id, date) REFERENCES history (account_id, date) ON DELETE CASCADE
+);
+CREATE TABLE IF NOT EXISTS tags (
+	account_id TEXT NOT NULL REFERENCES accounts (id) ON DELETE CASCADE,
+	position   INTEGER NOT NULL,
+	tag        TEXT NOT NULL
+);
+`
+
+type SQLite struct{}
+
+func openSQLite(filename string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", filename)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec("PRAGMA foreign_keys = ON"); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+func (SQLite) Read(filename string) ([]Account, error) {
+	if _, err := os.Stat(filename); err != nil {
+		return nil, err
+	}
+	db, err := openSQLite(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return nil, err
+	}
+	if version > SchemaVersion {
+		return nil, fmt.Errorf("schema version %d is newer than the supported version %d", version, SchemaVersion)
+	}
+
+	var accounts []Account
+	index := make(map[string]int)
+	rows, err := db.Query("SELECT id, name, kind, currency, closed FROM accounts ORDER BY position")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var account Account
+		if err := rows.Scan(&account.ID, &account.Name, &account.Kind, &account.Currency, &account.Closed); err != nil {
+			return nil, err
+		}
+		index[account.ID] = len(accounts)
+		accounts = append(accounts, account)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	history := make(map[string]map[string]int)
+	rows, err = db.Query("SELECT account_id, date, amount, change, withdrawal, income, fees FROM history ORDER BY account_id, date")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, date string
+		var amounts [5]string
+		if err := rows.Scan(&id, &date, &amounts[0], &amounts[1], &amounts[2], &amounts[3], &amounts[4]); err != nil {
+			return nil, err
+		}
+		entry := History{Date: date}
+		for i, field := range []*money.Amount{&entry.Amount, &entry.Change, &entry.Withdrawal, &entry.Income, &entry.Fees} {
+			if *field, err = money.ParseDecimal(amounts[i]); err != nil {
+				return nil, fmt.Errorf("%s %s: %w", id, date, err)
+			}
+		}
+		account := &accounts[index[id]]
+		if history[id] == nil {
+			history[id] = make(map[string]int)
+		}
+		history[id][date] = len(account.History)
+		account.History = append(account.History, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = db.Query("SELECT account_id, date, security, units, price FROM holdings ORDER BY account_id, date, position")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, date string
+		var holding Holding
+		if err := rows.Scan(&id, &date, &holding.Security, &holding.Units, &holding.Price); err != nil {
+			return nil, err
+		}
+		entry := &accounts[index[id]].History[history[id][date]]
+		entry.Holdings = append(entry.Holdings, holding)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = db.Query("SELECT account_id, date, id, other, amount FROM transfers ORDER BY account_id, date, position")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, date, amount string
+		var transfer Transfer
+		if err := rows.Scan(&id, &date, &transfer.ID, &transfer.Account, &amount); err != nil {
+			return nil, err
+		}
+		if transfer.Amount, err = money.ParseDecimal(amount); err != nil {
+			return nil, fmt.Errorf("%s %s: %w", id, date, err)
+		}
+		entry := &accounts[index[id]].History[history[id][date]]
+		entry.Transfers = append(entry.Transfers, transfer)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = db.Query("SELECT account_id, tag FROM tags ORDER BY account_id, position")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, tag string
+		if err := rows.Scan(&id, &tag); err != nil {
+			return nil, err
+		}
+		accounts[index[id]].Tags = append(accounts[index[id]].Tags, tag)
+	}
+	return accounts, rows.Err()
+}
+
+func (SQLite) Write(filename string, accounts []Account) error {
+	db, err := openSQLite(filename)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		return fmt.Errorf("could not create tables in %s: %w", filename, err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", SchemaVersion)); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM accounts"); err != nil {
+		return err
+	}
+	for position, account := range accounts {
+		_, err := tx.Exec("INSERT INTO accounts (id, position, name, kind, currency, closed) VALUES (?, ?, ?, ?, ?, ?)",
+			account.ID, position, account.Name, account.Kind, account.Currency, account.Closed)
+		if err != nil {
+			return fmt.Errorf("could not write %s to %s: %w", account.ID, filename, err)
+		}
+		for _, entry := range account.History {
+			_, err := tx.Exec("INSERT INTO history (account_id, date, amount, change, withdrawal, income, fees) VALUES (?, ?, ?, ?, ?, ?, ?)",
+				account.ID, entry.Date, entry.Amount.String(), entry.Change.String(), entry.Withdrawal.String(), entry.Income.String(), entry.Fees.String())
+			if err != nil {
+				return fmt.Errorf("could not write %s %s to %s: %w", account.ID, entry.Date, filename, err)
+			}
+			for i, holding := range entry.Holdings {
+				_, err := tx.Exec("INSERT INTO holdings (account_id, date, position, security, units, price) VALUES (?, ?, ?, ?, ?, ?)",
+					account.ID, entry.Date, i, holding.Security, holding.Units, holding.Price)
+				if err != nil {
+					return fmt.Errorf("could not write %s %s to %s: %w", account.ID, entry.Date, filename, err)
+				}
+			}
+			for i, transfer := range entry.Transfers {
+				_, err := tx.Exec("INSERT INTO transfers (account_id, date, position, id, other, amount) VALUES (?, ?, ?, ?, ?, ?)",
+					account.ID, entry.Date, i, transfer.ID, transfer.Account, transfer.Amount.String())
+				if err != nil {
+					return fmt.Errorf("could not write %s %s to %s: %w", account.ID, entry.Date, filename, err)
+				}
+			}
+		}
+		for i, tag := range account.Tags {
+			if _, err := tx.Exec("INSERT INTO tags (account_id, position, tag) VALUES (?, ?, ?)", account.ID, i, tag); err != nil {
+				return fmt.Errorf("could not write %s to %s: %w", account.ID, filename, err)
+			}
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,117 @@
+package history
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type Storage interface {
+	Read(filename string) ([]Account, error)
+	Write(filename string, accounts []Account) error
+}
+
+var Storages = []string{"yaml", "sqlite"}
+
+func StorageFor(name string, key *Key) (Storage, error) {
+	switch name {
+	case "yaml":
+		return YAML{Key: key}, nil
+	case "sqlite":
+		if key != nil {
+			return nil, fmt.Errorf("Encryption is only supported by the yaml storage")
+		}
+		return SQLite{}, nil
+	}
+	return nil, fmt.Errorf("No such storage: %s", name)
+}
+
+type YAML struct {
+	Key *Key
+}
+
+func (y YAML) Read(filename string) ([]Account, error) {
+	content, err := y.content(filename)
+	if err != nil {
+		return nil, err
+	}
+	return readYAML(bytes.NewReader(content))
+}
+
+func (y YAML) content(filename string) ([]byte, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	if isEncrypted(content) {
+		if y.Key == nil {
+			return nil, fmt.Errorf("%s is encrypted and no passphrase was given", filename)
+		}
+		return y.Key.decrypt(content)
+	}
+	return content, nil
+}
+
+const defaultIndent = 4
+
+func indentation(content []byte) int {
+	indent := 0
+	for _, line := range strings.Split(string(content), "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		spaces := len(line) - len(trimmed)
+		if trimmed == "" || spaces == 0 || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if indent == 0 || spaces < indent {
+			indent = spaces
+		}
+	}
+	if indent < 2 || indent > 9 {
+		return defaultIndent
+	}
+	return indent
+}
+
+// Write updates the existing documents so that comments survive.
+func (y YAML) Write(filename string, accounts []Account) error {
+	var header *yaml.Node
+	var existing []*yaml.Node
+	indent := defaultIndent
+	if previous, err := y.content(filename); err == nil {
+		if header, existing, _, err = decodeDocuments(bytes.NewReader(previous)); err != nil {
+			header, existing = nil, nil
+		}
+		indent = indentation(previous)
+	}
+	documents, err := mergeDocuments(header, existing, accounts)
+	if err != nil {
+		return fmt.Errorf("could not write %s: %w", filename, err)
+	}
+
+	var content bytes.Buffer
+	encoder := yaml.NewEncoder(&content)
+	encoder.SetIndent(indent)
+	for _, document := range documents {
+		err := encoder.Encode(document)
+		if err != nil {
+			return fmt.Errorf("could not write entry to %s: %w", filename, err)
+		}
+	}
+	if err := encoder.Close(); err != nil {
+		return err
+	}
+	data := content.Bytes()
+	if y.Key != nil {
+		if data, err = y.Key.encrypt(data); err != nil {
+			return fmt.Errorf("could not encrypt %s: %w", filename, err)
+		}
+	}
+	return writeAtomic(filename, func(writer io.Writer) error {
+		_, err := writer.Write(data)
+		return err
+	})
+}
@@ -0,0 +1,66 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jwiklund/ah/money"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSQLiteStorage(t *testing.T) {
+	accounts := transferAccounts(t)
+	assert.NoError(t, accounts.UpdateIncome("bank", "2023", money.FromFloat(1.5)))
+	assert.NoError(t, accounts.AddTag("bank", "cash"))
+	assert.NoError(t, accounts.AddTag("bank", "cash/bank"))
+	assert.NoError(t, accounts.AddEmptyAccount("fund"))
+	assert.NoError(t, accounts.UpdateHolding("fund", "2023", Holding{Security: "ABC", Units: 2.5, Price: 10}))
+	_, err := accounts.Transfer("bank", "broker", "2023", money.FromInt(30))
+	assert.NoError(t, err)
+
+	filename := filepath.Join(t.TempDir(), "accounts.db")
+	assert.NoError(t, accounts.SaveAs(SQLite{}, filename))
+	loaded, err := Open(SQLite{}, filename, false)
+	assert.NoError(t, err)
+	assert.Equal(t, accounts.accounts, loaded.accounts)
+
+	assert.NoError(t, loaded.DeleteAccount("fund"))
+	assert.NoError(t, loaded.Save(filename, 1))
+	assert.False(t, loaded.Dirty())
+	reloaded, err := Open(SQLite{}, filename, false)
+	assert.NoError(t, err)
+	assert.Len(t, reloaded.accounts, 2)
+
+	backups, err := Backups(filename)
+	assert.NoError(t, err)
+	assert.Len(t, backups, 1)
+	assert.NoError(t, reloaded.Restore(filename, backups[0].Name, 1))
+	assert.Len(t, reloaded.accounts, 3)
+}
+
+func TestMigrateStorage(t *testing.T) {
+	dir := t.TempDir()
+	accounts := transferAccounts(t)
+	assert.NoError(t, accounts.Save(filepath.Join(dir, "accounts.txt"), 0))
+
+	yaml, err := Load(filepath.Join(dir, "accounts.txt"), false)
+	assert.NoError(t, err)
+	assert.NoError(t, yaml.SaveAs(SQLite{}, filepath.Join(dir, "accounts.db")))
+	sqlite, err := Open(SQLite{}, filepath.Join(dir, "accounts.db"), false)
+	assert.NoError(t, err)
+	assert.NoError(t, sqlite.SaveAs(YAML{}, filepath.Join(dir, "copy.txt")))
+
+	original, _ := os.ReadFile(filepath.Join(dir, "accounts.txt"))
+	copied, _ := os.ReadFile(filepath.Join(dir, "copy.txt"))
+	assert.Equal(t, string(original), string(copied))
+}
+
+func TestStorageFor(t *testing.T) {
+	for _, name := range Storages {
+		_, err := StorageFor(name, nil)
+		assert.NoError(t, err)
+	}
+	_, err := StorageFor("csv", nil)
+	assert.EqualError(t, err, "No such storage: csv")
+}
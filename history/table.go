@@ -0,0 +1,153 @@
+package history
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type Table struct {
+	series map[string][]tableEntry
+}
+
+type tableEntry struct {
+	date  string
+	value float64
+}
+
+func NewTable() *Table {
+	return &Table{series: make(map[string][]tableEntry)}
+}
+
+func LoadTable(filename string) (*Table, error) {
+	reader, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	var table *Table
+	if strings.ToLower(filepath.Ext(filename)) == ".csv" {
+		table, err = LoadTableCsv(reader)
+	} else {
+		table, err = LoadTableYaml(reader)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %w", filename, err)
+	}
+	return table, nil
+}
+
+func LoadTableYaml(reader io.Reader) (*Table, error) {
+	var data map[string]map[string]float64
+	if err := yaml.NewDecoder(reader).Decode(&data); err != nil && err != io.EOF {
+		return nil, err
+	}
+	table := NewTable()
+	for key, values := range data {
+		for date, value := range values {
+			if err := table.Add(key, date, value); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return table, nil
+}
+
+func LoadTableCsv(reader io.Reader) (*Table, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = 3
+	csvReader.TrimLeadingSpace = true
+	table := NewTable()
+	for line := 1; ; line++ {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			return table, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		value, err := strconv.ParseFloat(record[2], 64)
+		if err != nil {
+			if line == 1 {
+				continue
+			}
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if err := table.Add(record[1], record[0], value); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+}
+
+func (t *Table) Add(key, date string, value float64) error {
+	if err := ValidateDate(date); err != nil {
+		return err
+	}
+	series := append(t.series[key], tableEntry{date, value})
+	sort.Slice(series, func(i, j int) bool {
+		return CompareDates(series[i].date, series[j].date) < 0
+	})
+	t.series[key] = series
+	return nil
+}
+
+func (t *Table) Has(key string) bool {
+	if t == nil {
+		return false
+	}
+	_, ok := t.series[key]
+	return ok
+}
+
+// Lookup returns the latest value at or before date, dates outside the series have no value.
+func (t *Table) Lookup(key, date string) (float64, bool) {
+	if t == nil {
+		return 0, false
+	}
+	series, ok := t.series[key]
+	if !ok || len(series) == 0 {
+		return 0, false
+	}
+	p, err := parsePeriod(date)
+	if err != nil {
+		return 0, false
+	}
+	last, _ := parsePeriod(series[len(series)-1].date)
+	index := sort.Search(len(series), func(i int) bool {
+		return CompareDates(series[i].date, date) > 0
+	})
+	if index == 0 || p.after(last) {
+		return 0, false
+	}
+	return series[index-1].value, true
+}
+
+func (t *Table) Dates(key string) []string {
+	if t == nil {
+		return nil
+	}
+	var dates []string
+	for _, entry := range t.series[key] {
+		dates = append(dates, entry.date)
+	}
+	return dates
+}
+
+func (t *Table) Keys() []string {
+	if t == nil {
+		return nil
+	}
+	var keys []string
+	for key := range t.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
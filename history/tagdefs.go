@@ -0,0 +1,99 @@
+package history
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type TagDefinition struct {
+	ExcludeFromIncrease bool `yaml:"exclude_from_increase"`
+	SortLast            bool `yaml:"sort_last"`
+	Hidden              bool `yaml:"hidden"`
+	Illiquid            bool `yaml:"illiquid"`
+}
+
+type TagDefinitions map[string]TagDefinition
+
+func ParseTagDefinitions(section map[string]any) (TagDefinitions, error) {
+	content, err := yaml.Marshal(section)
+	if err != nil {
+		return nil, err
+	}
+	definitions, err := LoadTagDefinitionsYaml(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("invalid tag definitions: %w", err)
+	}
+	return definitions, nil
+}
+
+func LoadTagDefinitionsYaml(reader io.Reader) (TagDefinitions, error) {
+	var definitions TagDefinitions
+	decoder := yaml.NewDecoder(reader)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&definitions); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return definitions, nil
+}
+
+var DefaultTagDefinitions = TagDefinitions{
+	Oneoff: {ExcludeFromIncrease: true, SortLast: true, Hidden: true},
+}
+
+// Only Hidden falls back to the last path segment of tag.
+func (d TagDefinitions) lookup(tag string) TagDefinition {
+	definition, ok := d[tag]
+	if index := strings.LastIndex(tag, "/"); !ok && index != -1 {
+		definition.Hidden = d[tag[index+1:]].Hidden
+	}
+	return definition
+}
+
+func (d TagDefinitions) account(account Account) TagDefinition {
+	var result TagDefinition
+	for _, tag := range account.Tags {
+		definition := d.lookup(tag)
+		result.ExcludeFromIncrease = result.ExcludeFromIncrease || definition.ExcludeFromIncrease
+		result.SortLast = result.SortLast || definition.SortLast
+		result.Hidden = result.Hidden || definition.Hidden
+		result.Illiquid = result.Illiquid || definition.Illiquid
+	}
+	return result
+}
+
+func (a *Accounts) SetTagDefinitions(definitions TagDefinitions) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	merged := make(TagDefinitions)
+	for tag, definition := range DefaultTagDefinitions {
+		merged[tag] = definition
+	}
+	for tag, definition := range definitions {
+		merged[tag] = definition
+	}
+	a.tags = merged
+	a.accounts = sortAccounts(a.accounts, merged)
+}
+
+func (a *Accounts) tagDefinitionsLocked() TagDefinitions {
+	if a.tags == nil {
+		return DefaultTagDefinitions
+	}
+	return a.tags
+}
+
+func (a *Accounts) TagDefinitions() TagDefinitions {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	result := make(TagDefinitions)
+	for tag, definition := range a.tagDefinitionsLocked() {
+		result[tag] = definition
+	}
+	return result
+}
@@ -0,0 +1,133 @@
+package history
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+type TagEntry struct {
+	Tag        string
+	Definition TagDefinition
+	Accounts   []TagAccount
+}
+
+type TagAccount struct {
+	ID   string
+	Name string
+}
+
+func (a *Accounts) Tags() []TagEntry {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	byTag := make(map[string][]TagAccount)
+	for _, account := range a.accounts {
+		for _, tag := range account.Tags {
+			byTag[tag] = append(byTag[tag], TagAccount{ID: account.ID, Name: account.Name})
+		}
+	}
+	definitions := a.tagDefinitionsLocked()
+	var result []TagEntry
+	for tag, accounts := range byTag {
+		result = append(result, TagEntry{Tag: tag, Definition: definitions.lookup(tag), Accounts: accounts})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Tag < result[j].Tag
+	})
+	return result
+}
+
+func checkTag(tag string) error {
+	if tag == "" {
+		return errors.New("Tag must not be empty")
+	}
+	return nil
+}
+
+func (a *Accounts) AddTag(id string, tag string) error {
+	tag = strings.TrimSpace(tag)
+	if err := checkTag(tag); err != nil {
+		return err
+	}
+	return a.updateAccount(id, func(account *Account) error {
+		if slices.Contains(account.Tags, tag) {
+			return fmt.Errorf("%s already has tag %s", account.Name, tag)
+		}
+		account.Tags = append(slices.Clone(account.Tags), tag)
+		return nil
+	})
+}
+
+func (a *Accounts) RemoveTag(id string, tag string) error {
+	return a.updateAccount(id, func(account *Account) error {
+		index := slices.Index(account.Tags, tag)
+		if index == -1 {
+			return fmt.Errorf("%s does not have tag %s", account.Name, tag)
+		}
+		account.Tags = slices.Delete(slices.Clone(account.Tags), index, index+1)
+		return nil
+	})
+}
+
+func (a *Accounts) RenameTag(tag string, newTag string) (int, error) {
+	newTag = strings.TrimSpace(newTag)
+	if err := checkTag(newTag); err != nil {
+		return 0, err
+	}
+	return a.renameTags(func(t string) (string, bool) {
+		return newTag, t == tag
+	})
+}
+
+func (a *Accounts) RenameTagPrefix(prefix string, newPrefix string) (int, error) {
+	prefix = strings.TrimSuffix(prefix, "/")
+	newPrefix = strings.TrimSuffix(strings.TrimSpace(newPrefix), "/")
+	if prefix == "" {
+		return 0, errors.New("Prefix must not be empty")
+	}
+	return a.renameTags(func(t string) (string, bool) {
+		rest, ok := strings.CutPrefix(t, prefix)
+		if !ok || (rest != "" && !strings.HasPrefix(rest, "/")) {
+			return t, false
+		}
+		if newPrefix == "" {
+			rest = strings.TrimPrefix(rest, "/")
+		}
+		renamed := newPrefix + rest
+		return renamed, renamed != ""
+	})
+}
+
+func (a *Accounts) renameTags(rename func(string) (string, bool)) (int, error) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	var changed []string
+	for i, account := range a.accounts {
+		var tags []string
+		accountChanged := false
+		for _, tag := range account.Tags {
+			if renamed, ok := rename(tag); ok {
+				tag = renamed
+				accountChanged = true
+			}
+			if !slices.Contains(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+		if accountChanged {
+			a.accounts[i].Tags = tags
+			changed = append(changed, account.ID)
+		}
+	}
+	if len(changed) == 0 {
+		return 0, errors.New("No accounts with matching tags")
+	}
+	a.accounts = sortAccounts(a.accounts, a.tagDefinitionsLocked())
+	a.changedLocked(changed...)
+	return len(changed), nil
+}
@@ -0,0 +1,121 @@
+package history
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jwiklund/ah/money"
+	"github.com/stretchr/testify/assert"
+)
+
+const tagExample = `
+name: ips
+history:
+- date: "2022"
+tags: [pension, pension/ips]
+---
+name: isk
+history:
+- date: "2022"
+tags: [savings]
+`
+
+func tagAccounts(t *testing.T) *Accounts {
+	return testAccounts(t, tagExample)
+}
+
+func TestAddRemoveTag(t *testing.T) {
+	accounts := tagAccounts(t)
+	assert.Error(t, accounts.AddTag("ips", "pension"))
+	assert.Error(t, accounts.AddTag("ips", " "))
+	assert.Error(t, accounts.AddTag("missing", "pension"))
+	assert.NoError(t, accounts.RemoveTag("ips", "pension"))
+	assert.Error(t, accounts.RemoveTag("ips", "pension"))
+
+	account, _ := accounts.Account("ips")
+	assert.Equal(t, []string{"pension/ips"}, account.Tags)
+}
+
+func TestTags(t *testing.T) {
+	accounts := tagAccounts(t)
+	assert.NoError(t, accounts.AddTag("isk", "pension"))
+	assert.Equal(t, []TagEntry{
+		{Tag: "pension", Accounts: []TagAccount{{ID: "ips", Name: "ips"}, {ID: "isk", Name: "isk"}}},
+		{Tag: "pension/ips", Accounts: []TagAccount{{ID: "ips", Name: "ips"}}},
+		{Tag: "savings", Accounts: []TagAccount{{ID: "isk", Name: "isk"}}},
+	}, accounts.Tags())
+}
+
+func TestRenameTag(t *testing.T) {
+	accounts := tagAccounts(t)
+	changed, err := accounts.RenameTag("savings", "pension")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, changed)
+	_, err = accounts.RenameTag("savings", "pension")
+	assert.Error(t, err)
+
+	assert.NoError(t, accounts.AddTag("isk", "pensionfund"))
+	changed, err = accounts.RenameTagPrefix("pension", "retirement")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, changed)
+	account, _ := accounts.Account("ips")
+	assert.Equal(t, []string{"retirement", "retirement/ips"}, account.Tags)
+	account, _ = accounts.Account("isk")
+	assert.Equal(t, []string{"retirement", "pensionfund"}, account.Tags)
+
+	changed, err = accounts.RenameTagPrefix("retirement/", "")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, changed)
+	account, _ = accounts.Account("ips")
+	assert.Equal(t, []string{"retirement", "ips"}, account.Tags)
+}
+
+func TestTagDefinitions(t *testing.T) {
+	accounts := tagAccounts(t)
+	assert.NoError(t, accounts.AddAccount("house", "2022"))
+	assert.NoError(t, accounts.UpdateAmount("house", "2022", 100))
+	assert.NoError(t, accounts.UpdateAmount("ips", "2022", 10))
+	assert.NoError(t, accounts.UpdateAmount("isk", "2022", 10))
+	assert.NoError(t, accounts.AddTag("house", "property"))
+	assert.NoError(t, accounts.AddTag("house", "property/Oneoff"))
+
+	accounts.SetTagDefinitions(TagDefinitions{
+		"property": {SortLast: true, Illiquid: true},
+		"pension":  {Hidden: true},
+	})
+	summary, tags := accounts.Summary(ViewOptions{})
+	assert.Equal(t, []string{"property", "savings"}, tags)
+	assert.Equal(t, money.Amount(100), summary[0].Illiquid)
+
+	var names []string
+	for _, current := range accounts.Current(ViewOptions{}) {
+		names = append(names, current.Name)
+	}
+	assert.Equal(t, []string{"ips", "isk", "house"}, names)
+	assert.Equal(t, TagDefinition{Hidden: true}, DefaultTagDefinitions.lookup("property/Oneoff"))
+}
+
+func TestLoadTagDefinitions(t *testing.T) {
+	definitions, err := LoadTagDefinitionsYaml(strings.NewReader(`
+house:
+  illiquid: true
+  sort_last: true
+gift:
+  exclude_from_increase: true
+  hidden: true
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, TagDefinitions{
+		"house": {Illiquid: true, SortLast: true},
+		"gift":  {ExcludeFromIncrease: true, Hidden: true},
+	}, definitions)
+
+	_, err = LoadTagDefinitionsYaml(strings.NewReader("house:\n  sortlast: true\n"))
+	assert.Error(t, err)
+
+	definitions, err = ParseTagDefinitions(map[string]any{"house": map[string]any{"sort_last": true}})
+	assert.NoError(t, err)
+	assert.Equal(t, TagDefinitions{"house": {SortLast: true}}, definitions)
+	_, err = ParseTagDefinitions(map[string]any{"house": map[string]any{"sortlast": true}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,222 @@
+package history
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jwiklund/ah/money"
+	"golang.org/x/exp/slices"
+)
+
+// Amount is what the leg adds to its own account.
+type Transfer struct {
+	ID      string       `yaml:"id"`
+	Account string       `yaml:"account"`
+	Amount  money.Amount `yaml:"amount"`
+}
+
+func (h History) transfers() money.Amount {
+	var sum money.Amount
+	for _, transfer := range h.Transfers {
+		sum = sum + transfer.Amount
+	}
+	return sum
+}
+
+func (a *Accounts) Transfer(from, to, date string, amount money.Amount) (string, error) {
+	if err := ValidateDate(date); err != nil {
+		return "", err
+	}
+	if from == to {
+		return "", errors.New("Can not transfer to the same account")
+	}
+	if amount <= 0 {
+		return "", errors.New("Transfer amount must be positive")
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	fromIndex, err := a.indexLocked(from)
+	if err != nil {
+		return "", err
+	}
+	toIndex, err := a.indexLocked(to)
+	if err != nil {
+		return "", err
+	}
+	for _, index := range []int{fromIndex, toIndex} {
+		if account := a.accounts[index]; account.Closed != "" && CompareDates(account.Closed, date) <= 0 {
+			return "", fmt.Errorf("%s is closed since %s", account.Name, account.Closed)
+		}
+	}
+	fromRate, err := a.rateLocked(a.accounts[fromIndex].Currency, date)
+	if err != nil {
+		return "", err
+	}
+	toRate, err := a.rateLocked(a.accounts[toIndex].Currency, date)
+	if err != nil {
+		return "", err
+	}
+	received := amount.Mul(fromRate / toRate)
+
+	id := a.nextTransferIDLocked()
+	for _, leg := range []struct {
+		index   int
+		account string
+		amount  money.Amount
+	}{
+		{fromIndex, to, -amount},
+		{toIndex, from, received},
+	} {
+		err := a.updateHistoryLocked(leg.index, func(history []History) ([]History, error) {
+			return addTransfer(history, date, Transfer{ID: id, Account: leg.account, Amount: leg.amount}), nil
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+	a.changedLocked(from, to)
+	return id, nil
+}
+
+func addTransfer(history []History, date string, transfer Transfer) []History {
+	index := slices.IndexFunc(history, func(h History) bool { return h.Date == date })
+	if index == -1 {
+		entry := History{Date: date}
+		for _, h := range history {
+			if CompareDates(h.Date, date) < 0 {
+				entry.Amount = h.Amount
+			}
+		}
+		history = append(history, entry)
+		index = len(history) - 1
+	}
+	history[index].Transfers = append(slices.Clone(history[index].Transfers), transfer)
+	return history
+}
+
+// Ids are never reused since undo can bring back a deleted transfer.
+func (a *Accounts) nextTransferIDLocked() string {
+	next := a.transferID + 1
+	for _, account := range a.accounts {
+		for _, h := range account.History {
+			for _, transfer := range h.Transfers {
+				if n, err := strconv.Atoi(strings.TrimPrefix(transfer.ID, "t")); err == nil && n >= next {
+					next = n + 1
+				}
+			}
+		}
+	}
+	a.transferID = next
+	return fmt.Sprintf("t%d", next)
+}
+
+func (a *Accounts) DeleteTransfer(id string) error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	var changed []string
+	for i, account := range a.accounts {
+		for j, h := range account.History {
+			index := slices.IndexFunc(h.Transfers, func(t Transfer) bool { return t.ID == id })
+			if index == -1 {
+				continue
+			}
+			changed = append(changed, account.ID)
+			transfers := slices.Delete(slices.Clone(h.Transfers), index, index+1)
+			if len(transfers) == 0 {
+				transfers = nil
+			}
+			a.accounts[i].History[j].Transfers = transfers
+		}
+	}
+	if len(changed) == 0 {
+		return fmt.Errorf("No such transfer: %s", id)
+	}
+	a.changedLocked(changed...)
+	return nil
+}
+
+type TransferLeg struct {
+	ID      string
+	Date    string
+	Account string
+	Other   string
+	Amount  money.Amount
+}
+
+func (a *Accounts) Transfers(id string) ([]TransferLeg, error) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	index, err := a.indexLocked(id)
+	if err != nil {
+		return nil, err
+	}
+	var result []TransferLeg
+	for _, h := range a.accounts[index].History {
+		for _, transfer := range h.Transfers {
+			result = append(result, TransferLeg{
+				ID:      transfer.ID,
+				Date:    h.Date,
+				Account: id,
+				Other:   transfer.Account,
+				Amount:  transfer.Amount,
+			})
+		}
+	}
+	return result, nil
+}
+
+func (a *Accounts) UnbalancedTransfers() []TransferLeg {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	legs := make(map[string][]TransferLeg)
+	currencies := make(map[string]string)
+	for _, account := range a.accounts {
+		currencies[account.ID] = a.currencyLocked(account)
+		for _, h := range account.History {
+			for _, transfer := range h.Transfers {
+				legs[transfer.ID] = append(legs[transfer.ID], TransferLeg{
+					ID:      transfer.ID,
+					Date:    h.Date,
+					Account: account.ID,
+					Other:   transfer.Account,
+					Amount:  transfer.Amount,
+				})
+			}
+		}
+	}
+	var result []TransferLeg
+	for _, pair := range legs {
+		if !balanced(pair, currencies) {
+			result = append(result, pair...)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].ID != result[j].ID {
+			return result[i].ID < result[j].ID
+		}
+		return result[i].Account < result[j].Account
+	})
+	return result
+}
+
+func balanced(pair []TransferLeg, currencies map[string]string) bool {
+	if len(pair) != 2 {
+		return false
+	}
+	first, second := pair[0], pair[1]
+	if first.Account != second.Other || second.Account != first.Other || first.Date != second.Date {
+		return false
+	}
+	if currencies[first.Account] == currencies[second.Account] {
+		return first.Amount == -second.Amount
+	}
+	return (first.Amount < 0 && second.Amount > 0) || (first.Amount > 0 && second.Amount < 0)
+}
@@ -0,0 +1,120 @@
+package history
+
+import (
+	"testing"
+
+	"github.com/jwiklund/ah/money"
+	"github.com/stretchr/testify/assert"
+)
+
+const transferExample = `
+name: bank
+history:
+- date: "2022"
+  amount: 100
+  change: 100
+- date: "2023"
+  amount: 70
+---
+name: broker
+history:
+- date: "2022"
+- date: "2023"
+  amount: 35
+`
+
+func transferAccounts(t *testing.T) *Accounts {
+	return testAccounts(t, transferExample)
+}
+
+func TestTransfer(t *testing.T) {
+	accounts := transferAccounts(t)
+	_, err := accounts.Transfer("bank", "bank", "2023", money.FromInt(30))
+	assert.Error(t, err)
+	_, err = accounts.Transfer("bank", "broker", "2023", money.FromInt(-30))
+	assert.Error(t, err)
+	id, err := accounts.Transfer("bank", "broker", "2023", money.FromInt(30))
+	assert.NoError(t, err)
+	assert.Equal(t, "t1", id)
+
+	account, _ := accounts.Account("bank")
+	assert.Equal(t, []Transfer{{ID: "t1", Account: "broker", Amount: money.FromInt(-30)}}, account.History[1].Transfers)
+	account, _ = accounts.Account("broker")
+	assert.Equal(t, []Transfer{{ID: "t1", Account: "bank", Amount: money.FromInt(30)}}, account.History[1].Transfers)
+	assert.Empty(t, accounts.UnbalancedTransfers())
+
+	_, history, _ := accounts.AccountHistory("broker", ViewOptions{})
+	assert.Equal(t, money.FromInt(30), history[1].Transfers)
+	assert.Equal(t, money.FromInt(5), history[1].Increase)
+	_, history, _ = accounts.AccountHistory("bank", ViewOptions{})
+	assert.Equal(t, money.FromInt(-30), history[1].Transfers)
+	assert.Equal(t, money.FromInt(0), history[1].Increase)
+
+	summary, _ := accounts.Summary(ViewOptions{})
+	assert.Equal(t, money.Amount(0), summary[1].Transfers)
+	assert.Equal(t, money.Amount(0), summary[1].Change)
+	assert.Equal(t, money.FromInt(5), summary[1].Increase)
+}
+
+func TestTransferBackdated(t *testing.T) {
+	accounts := transferAccounts(t)
+	_, err := accounts.Transfer("bank", "broker", "2023-06", money.FromInt(10))
+	assert.NoError(t, err)
+
+	account, _ := accounts.Account("bank")
+	assert.Equal(t, "2023-06", account.History[1].Date)
+	assert.Equal(t, money.FromInt(100), account.History[1].Amount)
+	account, _ = accounts.Account("broker")
+	assert.Equal(t, "2023-06", account.History[1].Date)
+	assert.Equal(t, money.Amount(0), account.History[1].Amount)
+}
+
+func TestUnbalancedTransfers(t *testing.T) {
+	accounts := transferAccounts(t)
+	_, err := accounts.Transfer("bank", "broker", "2023", money.FromInt(30))
+	assert.NoError(t, err)
+	id, err := accounts.Transfer("broker", "bank", "2023", money.FromInt(10))
+	assert.NoError(t, err)
+	assert.Equal(t, "t2", id)
+
+	assert.NoError(t, accounts.DeleteHistory("broker", "2023"))
+	assert.Equal(t, []TransferLeg{
+		{ID: "t1", Date: "2023", Account: "bank", Other: "broker", Amount: money.FromInt(-30)},
+		{ID: "t2", Date: "2023", Account: "bank", Other: "broker", Amount: money.FromInt(10)},
+	}, accounts.UnbalancedTransfers())
+
+	assert.NoError(t, accounts.DeleteTransfer("t1"))
+	assert.NoError(t, accounts.DeleteTransfer("t2"))
+	assert.Error(t, accounts.DeleteTransfer("t2"))
+	assert.Empty(t, accounts.UnbalancedTransfers())
+}
+
+func TestTransferIDNotReused(t *testing.T) {
+	accounts := transferAccounts(t)
+	id, err := accounts.Transfer("bank", "broker", "2023", money.FromInt(30))
+	assert.NoError(t, err)
+	assert.NoError(t, accounts.DeleteTransfer(id))
+	id, err = accounts.Transfer("bank", "broker", "2023", money.FromInt(30))
+	assert.NoError(t, err)
+	assert.Equal(t, "t2", id)
+}
+
+func TestTransferClosed(t *testing.T) {
+	accounts := transferAccounts(t)
+	assert.NoError(t, accounts.UpdateAmount("broker", "2023", 0))
+	assert.NoError(t, accounts.Close("broker", "2023"))
+	_, err := accounts.Transfer("bank", "broker", "2024", money.FromInt(30))
+	assert.EqualError(t, err, "broker is closed since 2023")
+	_, err = accounts.Transfer("broker", "bank", "2023", money.FromInt(30))
+	assert.EqualError(t, err, "broker is closed since 2023")
+}
+
+func TestBalancedCurrencies(t *testing.T) {
+	currencies := map[string]string{"bank": "SEK", "broker": "EUR"}
+	leg := func(account, other string, amount money.Amount) TransferLeg {
+		return TransferLeg{ID: "t1", Date: "2023", Account: account, Other: other, Amount: amount}
+	}
+	assert.True(t, balanced([]TransferLeg{leg("bank", "broker", -100), leg("broker", "bank", 10)}, currencies))
+	assert.False(t, balanced([]TransferLeg{leg("bank", "broker", 100), leg("broker", "bank", 10)}, currencies))
+	assert.False(t, balanced([]TransferLeg{leg("bank", "broker", 0), leg("broker", "bank", 10)}, currencies))
+}
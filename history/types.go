@@ -1,30 +1,70 @@
 package history
 
-import "sync"
+import (
+	"sync"
+
+	"github.com/jwiklund/ah/money"
+)
 
 const (
 	Oneoff string = "Oneoff"
 )
 
+type Kind string
+
+const (
+	Asset     Kind = "asset"
+	Liability Kind = "liability"
+)
+
+var Kinds = []Kind{Asset, Liability}
+
 type Accounts struct {
-	accounts []Account
-	lock     *sync.Mutex
+	accounts    []Account
+	currency    string
+	rates       *Table
+	cpi         *Table
+	tags        TagDefinitions
+	flowTiming  FlowTiming
+	transferID  int
+	revision    uint64
+	saved       uint64
+	file        fileState
+	storage     Storage
+	repository  *Repository
+	recorded    map[string]Account
+	events      []Event
+	pending     []Event
+	journalFile *journalFile
+	undo        []change
+	redo        []change
+	lock        *sync.Mutex
 }
 
 type Account struct {
-	Name    string    `yaml:"name"`
-	History []History `yaml:"history"`
-	Tags    []string  `yaml:"tags"`
+	ID       string    `yaml:"id"`
+	Name     string    `yaml:"name"`
+	Kind     Kind      `yaml:"kind,omitempty"`
+	Currency string    `yaml:"currency,omitempty"`
+	Closed   string    `yaml:"closed,omitempty"`
+	History  []History `yaml:"history"`
+	Tags     []string  `yaml:"tags"`
 }
 
 type History struct {
-	Date   string
-	Amount int
-	Change int
+	Date       string
+	Amount     money.Amount
+	Change     money.Amount
+	Withdrawal money.Amount `yaml:"withdrawal,omitempty"`
+	Income     money.Amount `yaml:"income,omitempty"`
+	Fees       money.Amount `yaml:"fees,omitempty"`
+	Holdings   []Holding    `yaml:"holdings,omitempty"`
+	Transfers  []Transfer   `yaml:"transfers,omitempty"`
 }
 
 func New() *Accounts {
 	return &Accounts{
-		lock: &sync.Mutex{},
+		storage: YAML{},
+		lock:    &sync.Mutex{},
 	}
 }
@@ -1,60 +1,208 @@
 package history
 
 import (
+	"errors"
 	"fmt"
+	"strings"
+
+	"github.com/jwiklund/ah/money"
+	"golang.org/x/exp/slices"
 )
 
 func (a *Accounts) AddAccount(name string, date string) error {
+	return a.addAccount(name, []History{{Date: date}})
+}
+
+func (a *Accounts) AddEmptyAccount(name string) error {
+	return a.addAccount(name, nil)
+}
+
+func (a *Accounts) addAccount(name string, history []History) error {
 	a.lock.Lock()
 	defer a.lock.Unlock()
 
-	slug := NameToSlug(name)
-	for _, a := range a.accounts {
-		if NameToSlug(a.Name) == slug {
-			return fmt.Errorf("Account %s collides with %s", name, a.Name)
-		}
+	if err := a.checkNameLocked("", name); err != nil {
+		return err
 	}
-
+	id := newID(a.accounts, name)
 	a.accounts = sortAccounts(append(a.accounts, Account{
-		Name: name,
-		History: []History{
-			{Date: date},
-		},
-	}))
+		ID:      id,
+		Name:    name,
+		History: history,
+	}), a.tagDefinitionsLocked())
+	a.changedLocked(id)
 	return nil
 }
 
-func (a *Accounts) AddEmptyAccount(name string) error {
+func (a *Accounts) checkNameLocked(id, name string) error {
+	if strings.TrimSpace(name) == "" {
+		return errors.New("Account name must not be empty")
+	}
+	for _, account := range a.accounts {
+		if account.ID == id {
+			continue
+		}
+		if strings.EqualFold(account.Name, name) {
+			return fmt.Errorf("Account %s already exists", account.Name)
+		}
+		if strings.Trim(NameToSlug(account.Name), "-") == strings.Trim(NameToSlug(name), "-") {
+			return fmt.Errorf("Account %s has the same slug as %s", name, account.Name)
+		}
+	}
+	return nil
+}
+
+func (a *Accounts) Rename(id string, name string) error {
+	name = strings.TrimSpace(name)
+	return a.updateAccount(id, func(account *Account) error {
+		if err := a.checkNameLocked(id, name); err != nil {
+			return err
+		}
+		account.Name = name
+		return nil
+	})
+}
+
+func (a *Accounts) Close(id string, date string) error {
+	if err := ValidateDate(date); err != nil {
+		return err
+	}
+	return a.updateAccount(id, func(account *Account) error {
+		var balance money.Amount
+		for _, h := range account.History {
+			if CompareDates(h.Date, date) <= 0 {
+				balance = h.Amount
+			}
+		}
+		if balance != 0 {
+			return fmt.Errorf("%s has a balance of %s at %s, withdraw or transfer it before closing", account.Name, balance.Human(), date)
+		}
+		account.Closed = date
+		return nil
+	})
+}
+
+func (a *Accounts) Reopen(id string) error {
+	return a.updateAccount(id, func(account *Account) error {
+		account.Closed = ""
+		return nil
+	})
+}
+
+func (a *Accounts) UpdateKind(id string, kind Kind) error {
+	if !slices.Contains(Kinds, kind) {
+		return fmt.Errorf("Unknown account kind: %s", kind)
+	}
+	return a.updateAccount(id, func(account *Account) error {
+		if kind == Asset {
+			kind = ""
+		}
+		account.Kind = kind
+		return nil
+	})
+}
+
+func (a *Accounts) UpdateCurrency(id string, currency string) error {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	return a.updateAccount(id, func(account *Account) error {
+		if currency != "" && currency != a.currency && !a.rates.Has(currency) {
+			return fmt.Errorf("No exchange rates for %s", currency)
+		}
+		if currency == a.currency {
+			currency = ""
+		}
+		account.Currency = currency
+		return nil
+	})
+}
+
+func (a *Accounts) updateAccount(id string, update func(*Account) error) error {
 	a.lock.Lock()
 	defer a.lock.Unlock()
 
-	slug := NameToSlug(name)
-	for _, a := range a.accounts {
-		if NameToSlug(a.Name) == slug {
-			return fmt.Errorf("Account %s collides with %s", name, a.Name)
-		}
+	index, err := a.indexLocked(id)
+	if err != nil {
+		return err
 	}
+	account := a.accounts[index]
+	if err := update(&account); err != nil {
+		return err
+	}
+	a.accounts[index] = account
+	a.accounts = sortAccounts(a.accounts, a.tagDefinitionsLocked())
+	a.changedLocked(account.ID)
+	return nil
+}
 
-	a.accounts = sortAccounts(append(a.accounts, Account{Name: name}))
+func (a *Accounts) DeleteAccount(id string) error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	index, err := a.indexLocked(id)
+	if err != nil {
+		return err
+	}
+	a.accounts = slices.Delete(a.accounts, index, index+1)
+	a.changedLocked(id)
 	return nil
 }
 
-func (a *Accounts) UpdateAmountBySlug(slug string, date string, newAmount int) error {
-	return a.UpdateHistoryBySlugDate(slug, date, func(h History) History {
-		h.Amount = newAmount
-		return h
+func (a *Accounts) DeleteHistory(id string, date string) error {
+	return a.UpdateHistory(id, func(history []History) ([]History, error) {
+		for index, entry := range history {
+			if entry.Date == date {
+				return slices.Delete(history, index, index+1), nil
+			}
+		}
+		return nil, fmt.Errorf("No entry for %s", date)
 	})
 }
 
-func (a *Accounts) UpdateChangeBySlug(slug string, date string, newChange int) error {
-	return a.UpdateHistoryBySlugDate(slug, date, func(h History) History {
+func (a *Accounts) UpdateAmount(id string, date string, newAmount money.Amount) error {
+	return a.UpdateHistory(id, func(history []History) ([]History, error) {
+		for index, entry := range history {
+			if entry.Date == date {
+				if len(entry.Holdings) > 0 && entry.Amount != newAmount {
+					return nil, fmt.Errorf("Amount at %s is the value of the holdings, update the holdings instead", date)
+				}
+				history[index].Amount = newAmount
+				return history, nil
+			}
+		}
+		return append(history, History{Date: date, Amount: newAmount}), nil
+	})
+}
+
+func (a *Accounts) UpdateChange(id string, date string, newChange money.Amount) error {
+	return a.UpdateHistoryByDate(id, date, func(h History) History {
 		h.Change = newChange
 		return h
 	})
 }
 
-func (a *Accounts) UpdateHistoryBySlugDate(slug string, date string, update func(History) History) error {
-	return a.UpdateHistoryBySlug(slug, func(history []History) ([]History, error) {
+func (a *Accounts) UpdateWithdrawal(id string, date string, newWithdrawal money.Amount) error {
+	return a.UpdateHistoryByDate(id, date, func(h History) History {
+		h.Withdrawal = newWithdrawal
+		return h
+	})
+}
+
+func (a *Accounts) UpdateIncome(id string, date string, newIncome money.Amount) error {
+	return a.UpdateHistoryByDate(id, date, func(h History) History {
+		h.Income = newIncome
+		return h
+	})
+}
+
+func (a *Accounts) UpdateFees(id string, date string, newFees money.Amount) error {
+	return a.UpdateHistoryByDate(id, date, func(h History) History {
+		h.Fees = newFees
+		return h
+	})
+}
+
+func (a *Accounts) UpdateHistoryByDate(id string, date string, update func(History) History) error {
+	return a.UpdateHistory(id, func(history []History) ([]History, error) {
 		for index, entry := range history {
 			if entry.Date == date {
 				history[index] = update(entry)
@@ -69,36 +217,93 @@ func (a *Accounts) UpdateHistoryBySlugDate(slug string, date string, update func
 	})
 }
 
+func (a *Accounts) UpdateHistory(id string, update func([]History) ([]History, error)) error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	index, err := a.indexLocked(id)
+	if err != nil {
+		return err
+	}
+	if err := a.updateHistoryLocked(index, update); err != nil {
+		return err
+	}
+	a.changedLocked(id)
+	return nil
+}
+
 func (a *Accounts) UpdateHistoryBySlug(slug string, update func([]History) ([]History, error)) error {
 	a.lock.Lock()
 	defer a.lock.Unlock()
 
+	index := -1
 	for i, account := range a.accounts {
-		if NameToSlug(account.Name) == slug {
-			newHistory, err := update(account.History)
-			if err == nil {
-				sortHistory(newHistory)
-				account.History = newHistory
-				a.accounts[i] = account
-			}
-			return err
+		if NameToSlug(account.Name) != slug {
+			continue
 		}
+		if index != -1 {
+			return fmt.Errorf("Ambiguous account: %s matches %s and %s", slug, a.accounts[index].Name, account.Name)
+		}
+		index = i
+	}
+	if index == -1 {
+		return fmt.Errorf("No such account: %s", slug)
+	}
+	if err := a.updateHistoryLocked(index, update); err != nil {
+		return err
 	}
-	return fmt.Errorf("No such account: %s", slug)
+	a.changedLocked(a.accounts[index].ID)
+	return nil
 }
 
-func (a *Accounts) AddYear(year string) error {
+func (a *Accounts) updateHistoryLocked(index int, update func([]History) ([]History, error)) error {
+	account := a.accounts[index]
+	newHistory, err := update(account.History)
+	if err != nil {
+		return err
+	}
+	for i := range newHistory {
+		newHistory[i] = newHistory[i].withHoldings()
+	}
+	sortHistory(newHistory)
+	account.History = newHistory
+	a.accounts[index] = account
+	return nil
+}
+
+func (a *Accounts) indexLocked(id string) (int, error) {
+	for i, account := range a.accounts {
+		if account.ID == id {
+			return i, nil
+		}
+	}
+	return -1, fmt.Errorf("No such account: %s", id)
+}
+
+func (a *Accounts) AddDate(date string) error {
+	if err := ValidateDate(date); err != nil {
+		return err
+	}
+
 	a.lock.Lock()
 	defer a.lock.Unlock()
 
+	var added []string
 	for i, account := range a.accounts {
+		if len(account.History) == 0 || account.closedBefore(date) {
+			continue
+		}
 		sortHistory(account.History)
 		last := account.History[len(account.History)-1]
-		if last.Date != year {
-			account.History = append(account.History, History{Date: year, Change: 0, Amount: last.Amount})
+		if CompareDates(last.Date, date) < 0 {
+			account.History = append(account.History, History{Date: date, Change: 0, Amount: last.Amount, Holdings: slices.Clone(last.Holdings)})
 			a.accounts[i] = account
+			added = append(added, account.ID)
 		}
 	}
+	if len(added) > 0 {
+		a.changedLocked(added...)
+	}
 
 	return nil
 }
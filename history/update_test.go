@@ -0,0 +1,113 @@
+package history
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddAccountID(t *testing.T) {
+	accounts := New()
+	assert.NoError(t, accounts.AddEmptyAccount("Savings"))
+	assert.EqualError(t, accounts.AddEmptyAccount("savings!"), "Account savings! has the same slug as Savings")
+	assert.Error(t, accounts.AddEmptyAccount("SAVINGS"))
+	assert.Error(t, accounts.AddEmptyAccount("-savings-"))
+	assert.Error(t, accounts.AddEmptyAccount(" "))
+	assert.NoError(t, accounts.Rename("savings", "Old savings"))
+	assert.NoError(t, accounts.AddEmptyAccount("Savings"))
+
+	var ids []string
+	for _, entry := range accounts.Current(ViewOptions{}) {
+		ids = append(ids, entry.ID)
+	}
+	assert.Equal(t, []string{"savings", "savings-2"}, ids)
+	assert.Error(t, accounts.Rename("savings", "savings?"))
+}
+
+func TestLoadFromAssignsID(t *testing.T) {
+	accounts, err := LoadFrom(bytes.NewBufferString("name: a b\n---\nid: a-b\nname: other\n---\nid: a-b\nname: third\n"))
+	assert.NoError(t, err)
+	var ids []string
+	for _, account := range accounts.accounts {
+		ids = append(ids, account.ID)
+	}
+	assert.Equal(t, []string{"a-b-2", "a-b", "third"}, ids)
+}
+
+func TestRename(t *testing.T) {
+	accounts := New()
+	assert.NoError(t, accounts.AddAccount("a", "2022"))
+	assert.NoError(t, accounts.AddAccount("b", "2022"))
+	assert.NoError(t, accounts.UpdateAmount("a", "2022", 10))
+	assert.Error(t, accounts.Rename("a", "B"))
+	assert.NoError(t, accounts.Rename("a", "c"))
+
+	name, history, err := accounts.AccountHistory("a", ViewOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "c", name)
+	assert.Equal(t, []SummaryEntry{{Year: "2022", End: 10, Increase: 10, Market: 10}}, history)
+	assert.Error(t, accounts.Rename("missing", "d"))
+}
+
+func TestDelete(t *testing.T) {
+	accounts := New()
+	assert.NoError(t, accounts.AddAccount("a", "2022"))
+	assert.NoError(t, accounts.AddAccount("b", "2022"))
+	assert.NoError(t, accounts.UpdateAmount("a", "2022", 10))
+	assert.NoError(t, accounts.UpdateAmount("a", "2023", 15))
+	assert.NoError(t, accounts.UpdateAmount("a", "2024", 20))
+	assert.NoError(t, accounts.UpdateAmount("b", "2023", 5))
+
+	assert.NoError(t, accounts.DeleteHistory("a", "2023"))
+	assert.Error(t, accounts.DeleteHistory("a", "2023"))
+	summary, _ := accounts.Summary(ViewOptions{})
+	assert.Equal(t, []SummaryEntry{
+		{Year: "2022", Assets: 10, End: 10, Increase: 10, Market: 10},
+		{Year: "2023", Assets: 15, Start: 10, End: 15, Increase: 5, Market: 5},
+		{Year: "2024", Assets: 20, Start: 15, End: 20, Increase: 5, Market: 5},
+	}, summary)
+
+	assert.NoError(t, accounts.DeleteAccount("b"))
+	assert.Error(t, accounts.DeleteAccount("b"))
+	summary, _ = accounts.Summary(ViewOptions{})
+	assert.Equal(t, []SummaryEntry{
+		{Year: "2022", Assets: 10, End: 10, Increase: 10, Market: 10},
+		{Year: "2024", Assets: 20, Start: 10, End: 20, Increase: 10, Market: 10},
+	}, summary)
+}
+
+func TestClose(t *testing.T) {
+	accounts := New()
+	assert.NoError(t, accounts.AddAccount("a", "2022"))
+	assert.NoError(t, accounts.AddAccount("b", "2022"))
+	assert.NoError(t, accounts.UpdateAmount("a", "2022", 10))
+	assert.NoError(t, accounts.UpdateAmount("b", "2022", 5))
+	assert.NoError(t, accounts.UpdateAmount("b", "2023", 7))
+	assert.Error(t, accounts.Close("b", "later"))
+	assert.EqualError(t, accounts.Close("b", "2022"), "b has a balance of 0.05 at 2022, withdraw or transfer it before closing")
+	assert.NoError(t, accounts.UpdateAmount("b", "2022", 0))
+	assert.NoError(t, accounts.UpdateWithdrawal("b", "2022", 5))
+	assert.NoError(t, accounts.Close("b", "2022"))
+	assert.NoError(t, accounts.AddDate("2023"))
+
+	_, history, err := accounts.AccountHistory("a", ViewOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, history, 2)
+
+	var ids []string
+	for _, entry := range accounts.Current(ViewOptions{}) {
+		ids = append(ids, entry.ID)
+	}
+	assert.Equal(t, []string{"a"}, ids)
+	assert.Len(t, accounts.Current(ViewOptions{Archived: true}), 2)
+
+	summary, _ := accounts.Summary(ViewOptions{})
+	assert.Equal(t, []SummaryEntry{
+		{Year: "2022", Assets: 10, End: 10, Change: -5, Withdrawals: 5, Increase: 15, Market: 15},
+		{Year: "2023", Assets: 10, Start: 10, End: 10},
+	}, summary)
+
+	assert.NoError(t, accounts.Reopen("b"))
+	assert.Len(t, accounts.Current(ViewOptions{}), 2)
+}
@@ -1,11 +1,13 @@
 package history
 
 import (
+	"fmt"
 	"regexp"
 	"sort"
 	"strings"
 	"time"
 
+	"github.com/jwiklund/ah/money"
 	"golang.org/x/exp/slices"
 )
 
@@ -15,11 +17,40 @@ func NameToSlug(name string) string {
 	return nameToSlugRegex.ReplaceAllString(strings.ToLower(name), "-")
 }
 
+func newID(accounts []Account, name string) string {
+	base := strings.Trim(NameToSlug(name), "-")
+	if base == "" {
+		base = "account"
+	}
+	id := base
+	for i := 2; slices.ContainsFunc(accounts, func(a Account) bool { return a.ID == id }); i++ {
+		id = fmt.Sprintf("%s-%d", base, i)
+	}
+	return id
+}
+
+func assignIDs(accounts []Account) {
+	seen := make(map[string]bool)
+	for i := range accounts {
+		if seen[accounts[i].ID] {
+			accounts[i].ID = ""
+		}
+		if accounts[i].ID != "" {
+			seen[accounts[i].ID] = true
+		}
+	}
+	for i := range accounts {
+		if accounts[i].ID == "" {
+			accounts[i].ID = newID(accounts, accounts[i].Name)
+		}
+	}
+}
+
 func currentDateLocked(accounts []Account) string {
 	var date string
 	for _, a := range accounts {
 		for _, h := range a.History {
-			if date == "" || strings.Compare(date, h.Date) < 0 {
+			if date == "" || CompareDates(date, h.Date) < 0 {
 				date = h.Date
 			}
 		}
@@ -30,13 +61,67 @@ func currentDateLocked(accounts []Account) string {
 	return time.Now().Format("2006")
 }
 
+func fillGaps(history []History, dates []string) []History {
+	if len(history) == 0 {
+		return history
+	}
+	var result []History
+	index := 0
+	for _, date := range dates {
+		if index >= len(history) {
+			break
+		}
+		if CompareDates(date, history[index].Date) >= 0 {
+			result = append(result, history[index])
+			index++
+			continue
+		}
+		if index > 0 {
+			result = append(result, History{Date: date, Amount: result[len(result)-1].Amount})
+		}
+	}
+	return result
+}
+
+func (a Account) kind() Kind {
+	if a.Kind == "" {
+		return Asset
+	}
+	return a.Kind
+}
+
+// value is the contribution of a balance to net worth.
+func (a Account) value(balance money.Amount) money.Amount {
+	if a.Kind == Liability {
+		return -balance
+	}
+	return balance
+}
+
+func (a Account) closedBefore(date string) bool {
+	return a.Closed != "" && CompareDates(a.Closed, date) < 0
+}
+
+func openHistory(account Account) []History {
+	if account.Closed == "" {
+		return account.History
+	}
+	var result []History
+	for _, h := range account.History {
+		if !account.closedBefore(h.Date) {
+			result = append(result, h)
+		}
+	}
+	return result
+}
+
 func sortHistory(history []History) {
 	sort.Slice(history, func(i, j int) bool {
-		return history[i].Date < history[j].Date
+		return CompareDates(history[i].Date, history[j].Date) < 0
 	})
 }
 
-func sortAccounts(accounts []Account) []Account {
+func sortAccounts(accounts []Account, definitions TagDefinitions) []Account {
 	histories := make([]History, 0, len(accounts))
 	for _, a := range accounts {
 		if len(a.History) == 0 {
@@ -50,12 +135,16 @@ func sortAccounts(accounts []Account) []Account {
 		indexes[i] = i
 	}
 	sort.Slice(indexes, func(i, j int) bool {
-		firstOneoff := slices.Contains(accounts[indexes[i]].Tags, Oneoff)
-		secondOneoff := slices.Contains(accounts[indexes[j]].Tags, Oneoff)
-		bothOneoff := firstOneoff && secondOneoff
-		eitherOneoff := firstOneoff || secondOneoff
-		if !bothOneoff && eitherOneoff {
-			return secondOneoff
+		firstClosed := accounts[indexes[i]].Closed != ""
+		secondClosed := accounts[indexes[j]].Closed != ""
+		if firstClosed != secondClosed {
+			return secondClosed
+		}
+
+		firstLast := definitions.account(accounts[indexes[i]]).SortLast
+		secondLast := definitions.account(accounts[indexes[j]]).SortLast
+		if firstLast != secondLast {
+			return secondLast
 		}
 
 		firstEmpty := histories[indexes[i]].Amount == 0 && histories[indexes[i]].Change == 0
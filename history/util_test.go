@@ -3,6 +3,7 @@ package history
 import (
 	"testing"
 
+	"github.com/jwiklund/ah/money"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -14,7 +15,7 @@ func TestSortAccount(t *testing.T) {
 	assert.Equal(t, []Account{
 		makeAccount("a", "2022", 1, false),
 		makeAccount("b", "2022", 1, false),
-	}, sortAccounts(accounts))
+	}, sortAccounts(accounts, DefaultTagDefinitions))
 }
 
 func TestSortAccountOneoff(t *testing.T) {
@@ -25,7 +26,7 @@ func TestSortAccountOneoff(t *testing.T) {
 	assert.Equal(t, []Account{
 		makeAccount("b", "2022", 1, false),
 		makeAccount("a", "2022", 1, true),
-	}, sortAccounts(accounts))
+	}, sortAccounts(accounts, DefaultTagDefinitions))
 }
 
 func TestSortAccountEmpty(t *testing.T) {
@@ -36,10 +37,10 @@ func TestSortAccountEmpty(t *testing.T) {
 	assert.Equal(t, []Account{
 		makeAccount("b", "2022", 1, false),
 		makeAccount("a", "2022", 0, false),
-	}, sortAccounts(accounts))
+	}, sortAccounts(accounts, DefaultTagDefinitions))
 }
 
-func makeAccount(name, year string, end int, oneoff bool) Account {
+func makeAccount(name, year string, end money.Amount, oneoff bool) Account {
 	var tags []string
 	if oneoff {
 		tags = append(tags, Oneoff)
@@ -0,0 +1,119 @@
+package history
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+type Finding struct {
+	Severity Severity
+	Account  string
+	Name     string
+	Entry    string
+	Message  string
+}
+
+func (f Finding) String() string {
+	location := f.Name
+	if location == "" {
+		location = f.Account
+	}
+	if f.Entry != "" {
+		location += " " + f.Entry
+	}
+	return fmt.Sprintf("%s: %s: %s", f.Severity, location, f.Message)
+}
+
+func (a *Accounts) Validate() []Finding {
+	unbalanced := a.UnbalancedTransfers()
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	var errors, warnings []Finding
+	slugs := make(map[string]string)
+	for _, account := range a.accounts {
+		finding := func(severity Severity, entry, format string, args ...any) {
+			f := Finding{Severity: severity, Account: account.ID, Name: account.Name, Entry: entry, Message: fmt.Sprintf(format, args...)}
+			if severity == SeverityError {
+				errors = append(errors, f)
+			} else {
+				warnings = append(warnings, f)
+			}
+		}
+		if strings.TrimSpace(account.Name) == "" {
+			finding(SeverityError, "", "Account name is empty")
+		} else if other, ok := slugs[NameToSlug(account.Name)]; ok {
+			finding(SeverityWarning, "", "Account name has the same slug as %s, imports cannot tell them apart", other)
+		} else {
+			slugs[NameToSlug(account.Name)] = account.Name
+		}
+		if account.Kind != "" && account.Kind != Asset && account.Kind != Liability {
+			finding(SeverityError, "", "Unknown account kind %s", account.Kind)
+		}
+		if account.Closed != "" && ValidateDate(account.Closed) != nil {
+			finding(SeverityError, "", "Invalid closing date %s", account.Closed)
+		}
+		if account.Currency != "" && account.Currency != a.currency && !a.rates.Has(account.Currency) {
+			finding(SeverityError, "", "No exchange rates for %s", account.Currency)
+		}
+		seen := make(map[string]bool)
+		for _, entry := range account.History {
+			if err := ValidateDate(entry.Date); err != nil {
+				finding(SeverityError, entry.Date, "Invalid date")
+			} else if account.Currency != "" && account.Currency != a.currency && a.rates.Has(account.Currency) {
+				if _, ok := a.rates.Lookup(account.Currency, entry.Date); !ok {
+					finding(SeverityError, entry.Date, "No exchange rate for %s", account.Currency)
+				}
+			}
+			if seen[entry.Date] {
+				finding(SeverityError, entry.Date, "Duplicate date")
+			}
+			seen[entry.Date] = true
+			for _, holding := range entry.Holdings {
+				if strings.TrimSpace(holding.Security) == "" {
+					finding(SeverityError, entry.Date, "Holding without security")
+				}
+			}
+		}
+	}
+	if a.cpi.Has(a.currency) {
+		missing := make(map[string]bool)
+		for _, account := range a.accounts {
+			for _, entry := range account.History {
+				year := RollUp(entry.Date, Yearly)
+				if _, ok := a.cpi.Lookup(a.currency, year); !ok && ValidDate(year) && !missing[year] {
+					missing[year] = true
+					warnings = append(warnings, Finding{
+						Severity: SeverityWarning,
+						Name:     "Consumer price index",
+						Entry:    year,
+						Message:  fmt.Sprintf("No index for %s, real values use the index of the year before", a.currency),
+					})
+				}
+			}
+		}
+	}
+	for _, leg := range unbalanced {
+		index, err := a.indexLocked(leg.Account)
+		name := leg.Account
+		if err == nil {
+			name = a.accounts[index].Name
+		}
+		warnings = append(warnings, Finding{
+			Severity: SeverityWarning,
+			Account:  leg.Account,
+			Name:     name,
+			Entry:    leg.Date,
+			Message:  fmt.Sprintf("Unbalanced transfer %s with %s", leg.ID, leg.Other),
+		})
+	}
+	return append(errors, warnings...)
+}
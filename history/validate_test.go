@@ -0,0 +1,75 @@
+package history
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	accounts, err := LoadFrom(strings.NewReader(`
+id: bank
+name: Bank
+currency: EUR
+history:
+  - date: "2022"
+    amount: 1
+    change: 0
+  - date: "2022"
+    amount: 2
+    change: 0
+  - date: "22"
+    amount: 2
+    change: 0
+---
+id: bank-2
+name: bank
+closed: never
+history: []
+---
+id: empty
+name: " "
+history: []
+`))
+	assert.NoError(t, err)
+	assert.Error(t, accounts.SetCurrency("SEK", nil))
+	assert.Equal(t, []Finding{
+		{Severity: SeverityError, Account: "bank", Name: "Bank", Message: "No exchange rates for EUR"},
+		{Severity: SeverityError, Account: "bank", Name: "Bank", Entry: "2022", Message: "Duplicate date"},
+		{Severity: SeverityError, Account: "bank", Name: "Bank", Entry: "22", Message: "Invalid date"},
+		{Severity: SeverityError, Account: "empty", Name: " ", Message: "Account name is empty"},
+		{Severity: SeverityError, Account: "bank-2", Name: "bank", Message: "Invalid closing date never"},
+		{Severity: SeverityWarning, Account: "bank-2", Name: "bank", Message: "Account name has the same slug as Bank, imports cannot tell them apart"},
+	}, accounts.Validate())
+
+	assert.Equal(t, "error: Bank 2022: Duplicate date", Finding{Severity: SeverityError, Account: "bank", Name: "Bank", Entry: "2022", Message: "Duplicate date"}.String())
+	assert.Empty(t, transferAccounts(t).Validate())
+}
+
+func TestValidateRates(t *testing.T) {
+	accounts, err := LoadFrom(strings.NewReader(`
+id: euro
+name: Euro
+currency: EUR
+history:
+  - date: "2022"
+    amount: 1
+    change: 1
+  - date: "2023"
+    amount: 1
+    change: 0
+`))
+	assert.NoError(t, err)
+	rates := NewTable()
+	assert.NoError(t, rates.Add("EUR", "2023", 11))
+	assert.NoError(t, accounts.SetCurrency("SEK", rates))
+	assert.Equal(t, []Finding{
+		{Severity: SeverityError, Account: "euro", Name: "Euro", Entry: "2022", Message: "No exchange rate for EUR"},
+	}, accounts.Validate())
+
+	cpi := NewTable()
+	assert.NoError(t, cpi.Add("SEK", "2022", 100))
+	accounts.SetCPI(cpi)
+	assert.Equal(t, Finding{Severity: SeverityWarning, Name: "Consumer price index", Entry: "2023", Message: "No index for SEK, real values use the index of the year before"}, accounts.Validate()[1])
+}
@@ -5,59 +5,146 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/jwiklund/ah/money"
 	"golang.org/x/exp/slices"
 )
 
-func (a *Accounts) AccountHistory(slug string) (string, []SummaryEntry, error) {
+type ViewOptions struct {
+	Tag         string
+	Granularity Granularity
+	Native      bool
+	Archived    bool
+	Real        string
+}
+
+func (a *Accounts) Account(id string) (Account, error) {
 	a.lock.Lock()
 	defer a.lock.Unlock()
 
-	for _, a := range a.accounts {
-		if NameToSlug(a.Name) != slug {
-			continue
+	for _, account := range a.accounts {
+		if account.ID == id {
+			account.Currency = a.currencyLocked(account)
+			account.History = slices.Clone(account.History)
+			account.Tags = slices.Clone(account.Tags)
+			return account, nil
+		}
+	}
+	return Account{}, fmt.Errorf("No such account: %s", id)
+}
+
+func (a *Accounts) AccountHistory(id string, opts ViewOptions) (string, []SummaryEntry, error) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	index, err := a.indexLocked(id)
+	if err != nil {
+		return "", nil, err
+	}
+	account := a.accounts[index]
+	history, err := a.accountHistoryLocked(account, opts)
+	return account.Name, history, err
+}
+
+func (a *Accounts) accountHistoryLocked(account Account, opts ViewOptions) ([]SummaryEntry, error) {
+	history, err := a.convertLocked(account, rollUpHistory(account.History, opts.Granularity), opts.Native)
+	if err != nil {
+		return nil, err
+	}
+	currency := a.currency
+	if opts.Native {
+		currency = a.currencyLocked(account)
+	}
+	a.deflateLocked(history, currency, opts.Real, account.Kind)
+	return history, nil
+}
+
+func (a *Accounts) convertLocked(account Account, history []History, native bool) ([]SummaryEntry, error) {
+	var summary []SummaryEntry
+	var current money.Amount
+	currentRate := 1.0
+	for _, h := range history {
+		rate := 1.0
+		if !native {
+			var err error
+			if rate, err = a.rateLocked(account.Currency, h.Date); err != nil {
+				return nil, err
+			}
 		}
-		var summary []SummaryEntry
-		current := 0
-		for _, h := range a.History {
-			summary = append(summary, SummaryEntry{
-				Year:     h.Date,
-				Start:    current,
-				End:      h.Amount,
-				Change:   h.Change,
-				Increase: h.Amount - current - h.Change,
-			})
-			current = h.Amount
+		start := current.Mul(currentRate)
+		end := h.Amount.Mul(rate)
+		contributions := h.Change.Mul(rate)
+		withdrawals := h.Withdrawal.Mul(rate)
+		change := contributions - withdrawals
+		income := h.Income.Mul(rate)
+		fees := h.Fees.Mul(rate)
+		transfers := h.transfers().Mul(rate)
+		fx := current.Mul(rate) - start
+		if account.Kind == Liability {
+			fx = -fx
 		}
-		return a.Name, summary, nil
+		increase := account.value(end-start) - change - transfers - fx
+		summary = append(summary, SummaryEntry{
+			Year:          h.Date,
+			Start:         start,
+			End:           end,
+			Change:        change,
+			Contributions: contributions,
+			Withdrawals:   withdrawals,
+			Transfers:     transfers,
+			Income:        income,
+			Fees:          fees,
+			FX:            fx,
+			Increase:      increase,
+			Market:        increase - income + fees,
+		})
+		current = h.Amount
+		currentRate = rate
 	}
-	return "", nil, fmt.Errorf("No such account: %s", slug)
+	return summary, nil
 }
 
 type SummaryEntry struct {
-	Year     string
-	Start    int
-	End      int
-	Change   int
-	Oneoff   int
-	Increase int
+	Year          string
+	Assets        money.Amount
+	Liabilities   money.Amount
+	Start         money.Amount
+	End           money.Amount
+	Change        money.Amount
+	Contributions money.Amount
+	Withdrawals   money.Amount
+	Transfers     money.Amount
+	Income        money.Amount
+	Fees          money.Amount
+	Oneoff        money.Amount
+	Illiquid      money.Amount
+	FX            money.Amount
+	Increase      money.Amount
+	Market        money.Amount
 }
 
-func (a *Accounts) Summary(tag string) ([]SummaryEntry, []string) {
+func (a *Accounts) Summary(opts ViewOptions) ([]SummaryEntry, []string) {
 	a.lock.Lock()
 	defer a.lock.Unlock()
 
+	return a.summaryLocked(opts)
+}
+
+func (a *Accounts) summaryLocked(opts ViewOptions) ([]SummaryEntry, []string) {
+	tag := opts.Tag
+	granularity := accountsGranularity(a.accounts, opts.Granularity)
+	definitions := a.tagDefinitionsLocked()
 	seenTags := make(map[string]bool)
 	summary := make(map[string]*SummaryEntry)
-	for _, a := range a.accounts {
-		if tag != "" && !slices.Contains(a.Tags, tag) {
+	var dates []string
+	var selected []Account
+	var flags []TagDefinition
+	var histories [][]History
+	for _, account := range a.accounts {
+		if tag != "" && !slices.Contains(account.Tags, tag) {
 			continue
 		}
-		oneoff := false
-		for _, accountTag := range a.Tags {
-			if accountTag == Oneoff {
-				oneoff = true
-			}
-			if strings.HasSuffix(accountTag, Oneoff) {
+		for _, accountTag := range account.Tags {
+			if definitions.lookup(accountTag).Hidden {
 				continue
 			}
 			if tag == "" && !strings.Contains(accountTag, "/") {
@@ -67,16 +154,45 @@ func (a *Accounts) Summary(tag string) ([]SummaryEntry, []string) {
 				seenTags[accountTag] = true
 			}
 		}
-		for _, h := range a.History {
-			entry, ok := summary[h.Date]
-			if !ok {
-				entry = &SummaryEntry{
+		selected = append(selected, account)
+		flags = append(flags, definitions.account(account))
+		histories = append(histories, rollUpHistory(openHistory(account), granularity))
+		for _, h := range histories[len(histories)-1] {
+			if _, ok := summary[h.Date]; !ok {
+				summary[h.Date] = &SummaryEntry{
 					Year: h.Date,
 				}
-				summary[h.Date] = entry
+				dates = append(dates, h.Date)
+			}
+		}
+	}
+	sort.Slice(dates, func(i, j int) bool {
+		return CompareDates(dates[i], dates[j]) < 0
+	})
+	for i, account := range selected {
+		converted, err := a.convertLocked(account, fillGaps(histories[i], dates), false)
+		if err != nil {
+			// left out of the totals, reported by Validate
+			continue
+		}
+		for _, h := range converted {
+			entry := summary[h.Year]
+			if account.Kind == Liability {
+				entry.Liabilities = entry.Liabilities + h.End
+			} else {
+				entry.Assets = entry.Assets + h.End
+			}
+			entry.End = entry.End + account.value(h.End)
+			if flags[i].Illiquid {
+				entry.Illiquid = entry.Illiquid + account.value(h.End)
 			}
-			entry.End = entry.End + h.Amount
-			if oneoff {
+			entry.FX = entry.FX + h.FX
+			entry.Contributions = entry.Contributions + h.Contributions
+			entry.Withdrawals = entry.Withdrawals + h.Withdrawals
+			entry.Transfers = entry.Transfers + h.Transfers
+			entry.Income = entry.Income + h.Income
+			entry.Fees = entry.Fees + h.Fees
+			if flags[i].ExcludeFromIncrease {
 				entry.Oneoff = entry.Oneoff - h.Change
 				entry.Change = entry.Change + h.Change
 			} else {
@@ -89,77 +205,68 @@ func (a *Accounts) Summary(tag string) ([]SummaryEntry, []string) {
 		tags = append(tags, accountTag)
 	}
 	sort.Strings(tags)
-	var dates []string
-	for date := range summary {
-		dates = append(dates, date)
-	}
-	sort.Strings(dates)
 	var result []SummaryEntry
-	current := 0
+	var current money.Amount
 	for _, date := range dates {
 		entry := summary[date]
 		entry.Start = current
-		entry.Increase = entry.End - entry.Change - entry.Start - entry.Oneoff
+		entry.Increase = entry.End - entry.Change - entry.Transfers - entry.Start - entry.Oneoff - entry.FX
+		entry.Market = entry.Increase - entry.Income + entry.Fees
 		current = entry.End
 		result = append(result, *entry)
 	}
+	a.deflateLocked(result, a.currency, opts.Real, Asset)
 	return result, tags
 }
 
 type CurrentEntry struct {
+	ID       string
 	Name     string
 	Slug     string
-	Start    int
-	End      int
-	Change   int
-	Increase int
+	Kind     Kind
+	Currency string
+	Closed   string
+	Start    money.Amount
+	End      money.Amount
+	Change   money.Amount
+	FX       money.Amount
+	Increase money.Amount
 }
 
-func (a *Accounts) Current() []CurrentEntry {
+func (a *Accounts) Current(opts ViewOptions) []CurrentEntry {
 	a.lock.Lock()
 	defer a.lock.Unlock()
 
-	date := currentDateLocked(a.accounts)
+	opts.Granularity = accountsGranularity(a.accounts, opts.Granularity)
+	date := RollUp(currentDateLocked(a.accounts), opts.Granularity)
 	var current []CurrentEntry
-	for _, a := range a.accounts {
-		lastIndex := len(a.History) - 1
-		if lastIndex == -1 {
-			current = append(current, CurrentEntry{
-				Name:     a.Name,
-				Slug:     NameToSlug(a.Name),
-				Start:    0,
-				End:      0,
-				Change:   0,
-				Increase: 0,
-			})
-		} else if a.History[lastIndex].Date != date {
-			current = append(current, CurrentEntry{
-				Name:     a.Name,
-				Slug:     NameToSlug(a.Name),
-				Start:    a.History[lastIndex].Amount,
-				End:      a.History[lastIndex].Amount,
-				Change:   0,
-				Increase: 0,
-			})
-		} else if lastIndex == 0 {
-			current = append(current, CurrentEntry{
-				Name:     a.Name,
-				Slug:     NameToSlug(a.Name),
-				Start:    0,
-				End:      a.History[lastIndex].Amount,
-				Change:   a.History[lastIndex].Change,
-				Increase: a.History[lastIndex].Amount - a.History[lastIndex].Change,
-			})
-		} else {
-			current = append(current, CurrentEntry{
-				Name:     a.Name,
-				Slug:     NameToSlug(a.Name),
-				Start:    a.History[lastIndex-1].Amount,
-				End:      a.History[lastIndex].Amount,
-				Change:   a.History[lastIndex].Change,
-				Increase: a.History[lastIndex].Amount - a.History[lastIndex].Change - a.History[lastIndex-1].Amount,
-			})
+	for _, account := range a.accounts {
+		if account.Closed != "" && !opts.Archived {
+			continue
+		}
+		entry := CurrentEntry{
+			ID:       account.ID,
+			Name:     account.Name,
+			Slug:     NameToSlug(account.Name),
+			Kind:     account.kind(),
+			Currency: a.currencyLocked(account),
+			Closed:   account.Closed,
+		}
+		history, err := a.accountHistoryLocked(account, opts)
+		if err == nil && len(history) > 0 {
+			last := history[len(history)-1]
+			if last.Year != date {
+				entry.Start = last.End
+				entry.End = last.End
+			} else {
+				entry.Start = last.Start
+				entry.End = last.End
+				entry.Change = last.Change
+				entry.FX = last.FX
+				entry.Increase = last.Increase
+			}
 		}
+		current = append(current, entry)
 	}
 	return current
 }
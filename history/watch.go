@@ -0,0 +1,212 @@
+package history
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/exp/slices"
+	"gopkg.in/yaml.v3"
+)
+
+var ErrConflict = errors.New("conflicting changes")
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+	hash    [sha256.Size]byte
+}
+
+type FileStatus string
+
+const (
+	FileUnchanged FileStatus = "unchanged"
+	FileReloaded  FileStatus = "reloaded"
+	FileConflict  FileStatus = "conflict"
+)
+
+func (a *Accounts) CheckFile(filename string) (FileStatus, error) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	return a.checkFileLocked(filename)
+}
+
+func (a *Accounts) checkFileLocked(filename string) (FileStatus, error) {
+	info, err := os.Stat(filename)
+	if os.IsNotExist(err) && a.file == (fileState{}) {
+		return FileUnchanged, nil
+	}
+	if err != nil {
+		return FileUnchanged, err
+	}
+	if info.ModTime().Equal(a.file.modTime) && info.Size() == a.file.size {
+		return FileUnchanged, nil
+	}
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return FileUnchanged, err
+	}
+	if sha256.Sum256(content) == a.file.hash {
+		a.file.modTime = info.ModTime()
+		return FileUnchanged, nil
+	}
+	if a.revision != a.saved {
+		return FileConflict, nil
+	}
+	loaded, state, err := loadFile(a.storage, filename)
+	if err != nil {
+		return FileUnchanged, err
+	}
+	a.accounts = sortAccounts(loaded.accounts, a.tagDefinitionsLocked())
+	a.file = state
+	// the reload can be undone like an edit, without making the accounts dirty
+	a.editedLocked("reload", a.allIDsLocked())
+	return FileReloaded, nil
+}
+
+type DiffLine struct {
+	Kind string
+	Text string
+}
+
+type AccountConflict struct {
+	ID     string
+	Name   string
+	Memory bool
+	Disk   bool
+	Diff   []DiffLine
+}
+
+func (a *Accounts) Conflicts(filename string) ([]AccountConflict, error) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	disk, _, err := loadFile(a.storage, filename)
+	if err != nil {
+		return nil, err
+	}
+	var result []AccountConflict
+	for _, id := range accountIDs(a.accounts, disk.accounts) {
+		memory, inMemory := findAccount(a.accounts, id)
+		onDisk, inDisk := findAccount(disk.accounts, id)
+		memoryLines, err := accountLines(memory, inMemory)
+		if err != nil {
+			return nil, err
+		}
+		diskLines, err := accountLines(onDisk, inDisk)
+		if err != nil {
+			return nil, err
+		}
+		if slices.Equal(memoryLines, diskLines) {
+			continue
+		}
+		name := memory.Name
+		if !inMemory {
+			name = onDisk.Name
+		}
+		result = append(result, AccountConflict{
+			ID:     id,
+			Name:   name,
+			Memory: inMemory,
+			Disk:   inDisk,
+			Diff:   diffLines(diskLines, memoryLines),
+		})
+	}
+	return result, nil
+}
+
+func (a *Accounts) Resolve(filename string, useDisk []string, keep int) error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	disk, state, err := loadFile(a.storage, filename)
+	if err != nil {
+		return err
+	}
+	var merged []Account
+	for _, id := range accountIDs(a.accounts, disk.accounts) {
+		source := a.accounts
+		if slices.Contains(useDisk, id) {
+			source = disk.accounts
+		}
+		if account, ok := findAccount(source, id); ok {
+			merged = append(merged, account)
+		}
+	}
+	a.accounts = sortAccounts(merged, a.tagDefinitionsLocked())
+	a.file = state
+	a.changedLocked(a.allIDsLocked()...)
+	if err := backup(filename, keep); err != nil {
+		return err
+	}
+	return a.saveLocked(filename)
+}
+
+func accountIDs(first, second []Account) []string {
+	var ids []string
+	for _, accounts := range [][]Account{first, second} {
+		for _, account := range accounts {
+			if !slices.Contains(ids, account.ID) {
+				ids = append(ids, account.ID)
+			}
+		}
+	}
+	return ids
+}
+
+func findAccount(accounts []Account, id string) (Account, bool) {
+	index := slices.IndexFunc(accounts, func(a Account) bool { return a.ID == id })
+	if index == -1 {
+		return Account{}, false
+	}
+	return accounts[index], true
+}
+
+func accountLines(account Account, ok bool) ([]string, error) {
+	if !ok {
+		return nil, nil
+	}
+	content, err := yaml.Marshal(account)
+	if err != nil {
+		return nil, fmt.Errorf("could not write %s: %w", account.ID, err)
+	}
+	return strings.Split(strings.TrimSuffix(string(content), "\n"), "\n"), nil
+}
+
+// diffLines is a longest common subsequence line diff.
+func diffLines(from, to []string) []DiffLine {
+	common := make([][]int, len(from)+1)
+	for i := range common {
+		common[i] = make([]int, len(to)+1)
+	}
+	for i := len(from) - 1; i >= 0; i-- {
+		for j := len(to) - 1; j >= 0; j-- {
+			if from[i] == to[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else {
+				common[i][j] = max(common[i+1][j], common[i][j+1])
+			}
+		}
+	}
+	var result []DiffLine
+	i, j := 0, 0
+	for i < len(from) || j < len(to) {
+		switch {
+		case i < len(from) && j < len(to) && from[i] == to[j]:
+			result = append(result, DiffLine{Kind: " ", Text: from[i]})
+			i++
+			j++
+		case i < len(from) && (j == len(to) || common[i+1][j] >= common[i][j+1]):
+			result = append(result, DiffLine{Kind: "-", Text: from[i]})
+			i++
+		default:
+			result = append(result, DiffLine{Kind: "+", Text: to[j]})
+			j++
+		}
+	}
+	return result
+}
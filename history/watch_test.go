@@ -0,0 +1,83 @@
+package history
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jwiklund/ah/money"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckFileReload(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "accounts.txt")
+	accounts := New()
+	assert.NoError(t, accounts.AddAccount("a", "2022"))
+	assert.NoError(t, accounts.Save(filename, 0))
+
+	status, err := accounts.CheckFile(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, FileUnchanged, status)
+
+	content, _ := os.ReadFile(filename)
+	assert.NoError(t, os.WriteFile(filename, []byte(strings.ReplaceAll(string(content), "name: a", "name: renamed")), 0644))
+	status, err = accounts.CheckFile(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, FileReloaded, status)
+	account, _ := accounts.Account("a")
+	assert.Equal(t, "renamed", account.Name)
+	assert.False(t, accounts.Dirty())
+	assert.Equal(t, "reload", accounts.Journal(JournalFilter{})[0].Action)
+
+	assert.NoError(t, accounts.Undo())
+	account, _ = accounts.Account("a")
+	assert.Equal(t, "a", account.Name)
+	assert.NoError(t, accounts.Undo())
+	_, err = accounts.Account("a")
+	assert.Error(t, err)
+}
+
+func TestSaveConflict(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "accounts.txt")
+	accounts := New()
+	assert.NoError(t, accounts.AddAccount("a", "2022"))
+	assert.NoError(t, accounts.AddAccount("b", "2022"))
+	assert.NoError(t, accounts.Save(filename, 0))
+
+	disk, err := Load(filename, false)
+	assert.NoError(t, err)
+	assert.NoError(t, disk.UpdateAmount("a", "2022", money.FromInt(10)))
+	assert.NoError(t, disk.UpdateAmount("b", "2022", money.FromInt(10)))
+	assert.NoError(t, disk.SaveCopy(filename))
+
+	assert.NoError(t, accounts.UpdateAmount("a", "2022", money.FromInt(20)))
+	err = accounts.Save(filename, 0)
+	assert.True(t, errors.Is(err, ErrConflict))
+
+	conflicts, err := accounts.Conflicts(filename)
+	assert.NoError(t, err)
+	assert.Len(t, conflicts, 2)
+	assert.Equal(t, "a", conflicts[0].ID)
+	assert.Contains(t, conflicts[0].Diff, DiffLine{Kind: "-", Text: "      amount: 10"})
+	assert.Contains(t, conflicts[0].Diff, DiffLine{Kind: "+", Text: "      amount: 20"})
+
+	assert.NoError(t, accounts.Resolve(filename, []string{"b"}, 0))
+	assert.False(t, accounts.Dirty())
+	loaded, err := Load(filename, false)
+	assert.NoError(t, err)
+	a, _ := loaded.Account("a")
+	b, _ := loaded.Account("b")
+	assert.Equal(t, money.FromInt(20), a.History[0].Amount)
+	assert.Equal(t, money.FromInt(10), b.History[0].Amount)
+}
+
+func TestDiffLines(t *testing.T) {
+	assert.Equal(t, []DiffLine{
+		{" ", "a"},
+		{"-", "b"},
+		{"+", "c"},
+		{" ", "d"},
+	}, diffLines([]string{"a", "b", "d"}, []string{"a", "c", "d"}))
+}
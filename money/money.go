@@ -0,0 +1,190 @@
+package money
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+	"gopkg.in/yaml.v3"
+)
+
+type Amount int64
+
+var (
+	minorUnits = 2
+	scale      = int64(100)
+)
+
+func SetMinorUnits(units int) error {
+	if units < 0 || units > 6 {
+		return fmt.Errorf("minor units must be between 0 and 6, was %d", units)
+	}
+	minorUnits = units
+	scale = int64(math.Pow10(units))
+	return nil
+}
+
+func MinorUnits() int {
+	return minorUnits
+}
+
+func FromInt(i int64) Amount {
+	return Amount(i * scale)
+}
+
+func FromFloat(f float64) Amount {
+	return Amount(math.Round(f * float64(scale)))
+}
+
+func (a Amount) Float() float64 {
+	return float64(a) / float64(scale)
+}
+
+func (a Amount) Mul(factor float64) Amount {
+	return Amount(math.Round(float64(a) * factor))
+}
+
+func (a Amount) Abs() Amount {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
+
+// Parse accepts both "1,234.50" and "1 234,50".
+func Parse(input string) (Amount, error) {
+	input = strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '\t', '\'', '\u00a0', '\u202f':
+			return -1
+		}
+		return r
+	}, input)
+	lastComma := strings.LastIndex(input, ",")
+	lastDot := strings.LastIndex(input, ".")
+	switch {
+	case lastComma != -1 && lastDot != -1:
+		if lastComma > lastDot {
+			input = strings.ReplaceAll(input, ".", "")
+			input = strings.Replace(input, ",", ".", 1)
+		} else {
+			input = strings.ReplaceAll(input, ",", "")
+		}
+	case lastComma != -1:
+		input = decimalOrGrouping(input, ",")
+	case lastDot != -1:
+		input = decimalOrGrouping(input, ".")
+	}
+	return ParseDecimal(input)
+}
+
+func decimalOrGrouping(input, separator string) string {
+	parts := strings.Split(input, separator)
+	if len(parts) > 2 || (len(parts[1]) == 3 && minorUnits < 3) {
+		return strings.Join(parts, "")
+	}
+	return strings.Join(parts, ".")
+}
+
+func ParseDecimal(input string) (Amount, error) {
+	if input == "" {
+		return 0, errors.New("empty amount")
+	}
+	negative := false
+	digits := input
+	switch digits[0] {
+	case '-':
+		negative = true
+		digits = digits[1:]
+	case '+':
+		digits = digits[1:]
+	}
+	whole, fraction, _ := strings.Cut(digits, ".")
+	fraction = strings.TrimRight(fraction, "0")
+	if len(fraction) > minorUnits {
+		return 0, fmt.Errorf("%s has more than %d decimals", input, minorUnits)
+	}
+	if whole == "" || !isDigits(whole) || !isDigits(fraction) {
+		return 0, fmt.Errorf("%s is not a number", input)
+	}
+	fraction = fraction + strings.Repeat("0", minorUnits-len(fraction))
+	value, err := strconv.ParseInt(whole+fraction, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s is not a number: %w", input, err)
+	}
+	if negative {
+		value = -value
+	}
+	return Amount(value), nil
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func (a Amount) parts() (string, string, string) {
+	sign := ""
+	value := int64(a)
+	if value < 0 {
+		sign = "-"
+		value = -value
+	}
+	whole := strconv.FormatInt(value/scale, 10)
+	if minorUnits == 0 {
+		return sign, whole, ""
+	}
+	return sign, whole, fmt.Sprintf("%0*d", minorUnits, value%scale)
+}
+
+func (a Amount) String() string {
+	sign, whole, fraction := a.parts()
+	if fraction == "" {
+		return sign + whole
+	}
+	return sign + whole + "." + fraction
+}
+
+func (a Amount) Human() string {
+	sign, _, fraction := a.parts()
+	whole := humanize.Comma(int64(a.Abs()) / scale)
+	if fraction == "" {
+		return sign + whole
+	}
+	return sign + whole + "." + fraction
+}
+
+func (a Amount) compact() string {
+	if int64(a)%scale == 0 {
+		return strconv.FormatInt(int64(a)/scale, 10)
+	}
+	return strings.TrimRight(a.String(), "0")
+}
+
+func (a Amount) MarshalYAML() (any, error) {
+	tag := "!!float"
+	if int64(a)%scale == 0 {
+		tag = "!!int"
+	}
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: a.compact()}, nil
+}
+
+func (a *Amount) UnmarshalYAML(node *yaml.Node) error {
+	value, err := ParseDecimal(node.Value)
+	if err != nil {
+		return fmt.Errorf("line %d: %w", node.Line, err)
+	}
+	*a = value
+	return nil
+}
+
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(a.compact()), nil
+}
@@ -0,0 +1,80 @@
+package money
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestParse(t *testing.T) {
+	for input, expected := range map[string]Amount{
+		"1":           100,
+		"-1":          -100,
+		"1,000":       100000,
+		"1 000":       100000,
+		"1.000":       100000,
+		"1,5":         150,
+		"1.5":         150,
+		"1,234.56":    123456,
+		"1.234,56":    123456,
+		"1 234 567,8": 123456780,
+		"1.000.000":   100000000,
+	} {
+		actual, err := Parse(input)
+		assert.NoError(t, err, input)
+		assert.Equal(t, expected, actual, input)
+	}
+	_, err := Parse("1.234,567")
+	assert.Error(t, err)
+	_, err = Parse("abc")
+	assert.Error(t, err)
+}
+
+func TestParseDecimal(t *testing.T) {
+	amount, err := ParseDecimal("12.50")
+	assert.NoError(t, err)
+	assert.Equal(t, Amount(1250), amount)
+	amount, err = ParseDecimal("12.500")
+	assert.NoError(t, err)
+	assert.Equal(t, Amount(1250), amount)
+	_, err = ParseDecimal("12.505")
+	assert.Error(t, err)
+	_, err = ParseDecimal("1,000")
+	assert.Error(t, err)
+}
+
+func TestFormat(t *testing.T) {
+	assert.Equal(t, "1234.50", Amount(123450).String())
+	assert.Equal(t, "1,234.50", Amount(123450).Human())
+	assert.Equal(t, "-0.05", Amount(-5).String())
+	assert.Equal(t, "-1,234,567.00", FromInt(-1234567).Human())
+}
+
+func TestYaml(t *testing.T) {
+	type entry struct {
+		Amount Amount
+		Change Amount
+	}
+	var decoded entry
+	assert.NoError(t, yaml.Unmarshal([]byte("amount: 1000\nchange: 10.5\n"), &decoded))
+	assert.Equal(t, entry{FromInt(1000), 1050}, decoded)
+
+	encoded, err := yaml.Marshal(decoded)
+	assert.NoError(t, err)
+	assert.Equal(t, "amount: 1000\nchange: 10.5\n", string(encoded))
+
+	assert.Error(t, yaml.Unmarshal([]byte("amount: 10.555\n"), &decoded))
+}
+
+func TestMinorUnits(t *testing.T) {
+	defer SetMinorUnits(2)
+	assert.NoError(t, SetMinorUnits(0))
+	amount, err := Parse("1,5")
+	assert.Error(t, err)
+	amount, err = Parse("1,500")
+	assert.NoError(t, err)
+	assert.Equal(t, Amount(1500), amount)
+	assert.Equal(t, "1,500", amount.Human())
+	assert.Error(t, SetMinorUnits(7))
+}
@@ -6,9 +6,11 @@ import (
 	"html/template"
 	"io"
 	"io/fs"
+	"math"
 	"os"
 
 	"github.com/dustin/go-humanize"
+	"github.com/jwiklund/ah/money"
 	"github.com/jwiklund/ah/view/assets"
 )
 
@@ -23,8 +25,9 @@ type CheckRenderer interface {
 }
 
 var funcMap = map[string]any{
-	"json":  toJson,
-	"human": toHuman,
+	"json":    toJson,
+	"human":   toHuman,
+	"percent": toPercent,
 }
 
 func New(assetsPath string) (Renderer, error) {
@@ -94,7 +97,26 @@ func toJson(data any) (template.JS, error) {
 	return template.JS(result), err
 }
 
+func toPercent(data any) string {
+	if p, ok := data.(*float64); ok {
+		if p == nil {
+			return "–"
+		}
+		data = *p
+	}
+	if f, ok := data.(float64); ok {
+		if math.Abs(f) < 0.0005 {
+			f = 0
+		}
+		return fmt.Sprintf("%.1f%%", f*100)
+	}
+	return fmt.Sprintf("%v", data)
+}
+
 func toHuman(data any) string {
+	if a, ok := data.(money.Amount); ok {
+		return a.Human()
+	}
 	if i, ok := data.(int); ok {
 		return humanize.Comma(int64(i))
 	}